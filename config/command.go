@@ -0,0 +1,63 @@
+package config
+
+import (
+	"errors"
+	"strings"
+)
+
+// SplitCommand splits a command line into arguments the way a POSIX shell splits words:
+// whitespace separates arguments, single quotes keep everything literally, and inside double
+// quotes or unquoted a backslash escapes the next character. Nothing else is interpreted, so
+// no variables, globs or pipes.
+func SplitCommand(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inWord := false
+	var quote rune // The open quote character, or 0
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\\' && (quote == 0 || (i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\'))):
+			if i+1 == len(runes) {
+				return nil, errors.New("command ends with a backslash")
+			}
+			i++
+			current.WriteRune(runes[i])
+			inWord = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				args = append(args, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.New("command has an unterminated quote")
+	}
+	if inWord {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
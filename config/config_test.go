@@ -0,0 +1,205 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfigMergesDefaults(t *testing.T) {
+	cfg := Config{
+		URLs: []URLConfig{
+			{Name: "plain", URL: "https://example.com"},
+			{
+				Name:         "own",
+				URL:          "https://example.org",
+				Cookies:      []Cookie{{Name: "consent", Value: "no"}, {Name: "session", Value: "abc"}},
+				LocalStorage: []LocalStorage{{Key: "theme", Value: "dark"}},
+			},
+		},
+		DefaultCookies: []Cookie{{Name: "consent", Value: "yes"}},
+		DefaultStorage: []LocalStorage{{Key: "theme", Value: "light"}, {Key: "tour", Value: "done"}},
+	}
+	if err := validateConfig(&cfg, true); err != nil {
+		t.Fatalf("validateConfig failed: %v", err)
+	}
+
+	plain := cfg.URLs[0]
+	if want := []Cookie{{Name: "consent", Value: "yes"}}; !reflect.DeepEqual(plain.Cookies, want) {
+		t.Errorf("plain cookies = %+v, want %+v", plain.Cookies, want)
+	}
+	if want := []LocalStorage{{Key: "theme", Value: "light"}, {Key: "tour", Value: "done"}}; !reflect.DeepEqual(plain.LocalStorage, want) {
+		t.Errorf("plain localStorage = %+v, want %+v", plain.LocalStorage, want)
+	}
+
+	own := cfg.URLs[1]
+	if want := []Cookie{{Name: "consent", Value: "no"}, {Name: "session", Value: "abc"}}; !reflect.DeepEqual(own.Cookies, want) {
+		t.Errorf("own cookies = %+v, want %+v", own.Cookies, want)
+	}
+	if want := []LocalStorage{{Key: "theme", Value: "dark"}, {Key: "tour", Value: "done"}}; !reflect.DeepEqual(own.LocalStorage, want) {
+		t.Errorf("own localStorage = %+v, want %+v", own.LocalStorage, want)
+	}
+
+	// The defaults are copied, not shared with the URLs
+	plain.Cookies[0].Value = "changed"
+	if cfg.DefaultCookies[0].Value != "yes" {
+		t.Errorf("default cookie changed through a URL's cookies")
+	}
+}
+
+func TestValidateConfigProfileSkipsDefaults(t *testing.T) {
+	cfg := Config{
+		URLs:           []URLConfig{{Name: "profiled", URL: "https://example.com", CookieProfileID: "guest"}},
+		CookieProfiles: []CookieProfile{{Name: "guest", Cookies: []Cookie{{Name: "role", Value: "guest"}}}},
+		DefaultCookies: []Cookie{{Name: "consent", Value: "yes"}},
+		DefaultStorage: []LocalStorage{{Key: "theme", Value: "light"}},
+	}
+	if err := validateConfig(&cfg, true); err != nil {
+		t.Fatalf("validateConfig failed: %v", err)
+	}
+	if want := []Cookie{{Name: "role", Value: "guest"}}; !reflect.DeepEqual(cfg.URLs[0].Cookies, want) {
+		t.Errorf("cookies = %+v, want %+v", cfg.URLs[0].Cookies, want)
+	}
+	if len(cfg.URLs[0].LocalStorage) != 0 {
+		t.Errorf("localStorage = %+v, want none", cfg.URLs[0].LocalStorage)
+	}
+}
+
+func TestURLListEntryUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    URLListEntry
+		wantErr bool
+	}{
+		{"string", `"https://example.com"`, URLListEntry{URL: "https://example.com"}, false},
+		{"empty string", `""`, URLListEntry{}, false},
+		{"object with url only", `{"url": "https://example.com"}`, URLListEntry{URL: "https://example.com"}, false},
+		{
+			"object with options",
+			`{"url": "https://example.com/blog", "name": "blog", "delay": 2500, "viewports": [{"width": 375, "height": 667}]}`,
+			URLListEntry{URL: "https://example.com/blog", Name: "blog", Delay: 2500, Viewports: []Viewport{{Width: 375, Height: 667}}},
+			false,
+		},
+		{"number", `42`, URLListEntry{}, true},
+		{"array", `["https://example.com"]`, URLListEntry{}, true},
+		{"bool", `true`, URLListEntry{}, true},
+		{"wrong field type", `{"url": "https://example.com", "delay": "slow"}`, URLListEntry{}, true},
+		{"truncated object", `{"url": "https://example.com"`, URLListEntry{}, true},
+	}
+	for _, tt := range tests {
+		var got URLListEntry
+		err := json.Unmarshal([]byte(tt.input), &got)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateConfigURLList(t *testing.T) {
+	var cfg Config
+	data := `{
+		"defaultDelay": 1500,
+		"urlList": [
+			"https://www.example.com/",
+			{"url": "https://example.org/docs", "name": "docs", "delay": 300, "viewports": [{"width": 800, "height": 600}]},
+			"  "
+		]
+	}`
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("decoding failed: %v", err)
+	}
+	if err := validateConfig(&cfg, true); err != nil {
+		t.Fatalf("validateConfig failed: %v", err)
+	}
+
+	if len(cfg.URLs) != 2 {
+		t.Fatalf("got %d URLs, want 2: %+v", len(cfg.URLs), cfg.URLs)
+	}
+	if got := cfg.URLs[0]; got.Name != "example.com" || got.URL != "https://www.example.com/" || got.Delay != 1500 {
+		t.Errorf("string entry became %+v", got)
+	}
+	if got := cfg.URLs[1]; got.Name != "docs" || got.Delay != 300 || len(got.Viewports) != 1 || got.Viewports[0].Width != 800 {
+		t.Errorf("object entry became %+v", got)
+	}
+
+	cfg = Config{URLList: []URLListEntry{{URL: "https://example.com", Delay: -1}}}
+	if err := validateConfig(&cfg, true); err == nil {
+		t.Errorf("expected an error for a negative delay")
+	}
+}
+
+func TestLoadConfigMergedNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		wantErr string
+		want    []string
+	}{
+		{
+			name:  "unnamed URLs in two files",
+			files: []string{`{"urls": [{"url": "https://example.com"}]}`, `{"urls": [{"url": "https://example.org"}]}`},
+			want:  []string{"page-1", "page-2"},
+		},
+		{
+			name:    "same name in two files",
+			files:   []string{`{"urls": [{"name": "home", "url": "https://example.com"}]}`, `{"urls": [{"name": "home", "url": "https://example.org"}]}`},
+			wantErr: `duplicate URL name "home"`,
+		},
+		{
+			name:    "same urlList domain in two files",
+			files:   []string{`{"urlList": ["https://example.com/a"]}`, `{"urlList": ["https://example.com/b"]}`},
+			wantErr: `duplicate URL name "example.com"`,
+		},
+		{
+			name:    "urls name and urlList name in two files",
+			files:   []string{`{"urls": [{"name": "docs", "url": "https://example.com"}]}`, `{"urlList": [{"url": "https://example.org", "name": "docs"}]}`},
+			wantErr: `duplicate URL name "docs"`,
+		},
+		{
+			name:  "same name within one file",
+			files: []string{`{"urls": [{"name": "home", "url": "https://example.com"}, {"name": "home", "url": "https://example.com/?v=2"}]}`},
+			want:  []string{"home", "home"},
+		},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		outputDir := filepath.Join(t.TempDir(), "screenshots")
+		for i, content := range tt.files {
+			// Every file sets the same outputDir so loading does not create one in the package
+			content = strings.Replace(content, "{", `{"outputDir": `+strconv.Quote(outputDir)+`, `, 1)
+			path := filepath.Join(dir, string(rune('a'+i))+".json")
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		cfg, err := LoadConfig(dir)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var names []string
+		for _, urlConfig := range cfg.URLs {
+			names = append(names, urlConfig.Name)
+		}
+		if !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("%s: names %v, want %v", tt.name, names, tt.want)
+		}
+	}
+}
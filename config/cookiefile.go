@@ -0,0 +1,56 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// httpOnlyPrefix marks HttpOnly cookies in Netscape cookie files
+const httpOnlyPrefix = "#HttpOnly_"
+
+// loadCookieFile parses a Netscape cookies.txt file. Each cookie line has seven
+// tab-separated fields: domain, include-subdomains flag, path, secure flag, expiry,
+// name and value. Domains keep their leading dot, which marks domain-wide cookies.
+func loadCookieFile(path string) ([]Cookie, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading cookie file: %w", err)
+	}
+	defer file.Close()
+
+	var cookies []Cookie
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		httpOnly := false
+		if strings.HasPrefix(line, httpOnlyPrefix) {
+			httpOnly = true
+			line = strings.TrimPrefix(line, httpOnlyPrefix)
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("cookie file %s line %d: expected 7 tab-separated fields, got %d", path, lineNum, len(fields))
+		}
+
+		cookies = append(cookies, Cookie{
+			Domain:   fields[0],
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Name:     fields[5],
+			Value:    fields[6],
+			HTTPOnly: httpOnly,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading cookie file %s: %w", path, err)
+	}
+
+	return cookies, nil
+}
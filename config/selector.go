@@ -0,0 +1,24 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ShadowSelectorSeparator separates the steps of a selector that pierces shadow roots, e.g.
+// "my-card::shadow .title": each step after the first is matched inside the shadow roots of
+// the previous step's matches
+const ShadowSelectorSeparator = "::shadow"
+
+// SplitShadowSelector splits selector into its steps, one CSS selector per shadow boundary. A
+// selector without ShadowSelectorSeparator is a single step.
+func SplitShadowSelector(selector string) ([]string, error) {
+	steps := strings.Split(selector, ShadowSelectorSeparator)
+	for i := range steps {
+		steps[i] = strings.TrimSpace(steps[i])
+		if steps[i] == "" {
+			return nil, fmt.Errorf("selector %q has an empty step around %s", selector, ShadowSelectorSeparator)
+		}
+	}
+	return steps, nil
+}
@@ -5,7 +5,6 @@ import (
 	"flag"
 	"log"
 	"os"
-	"os/exec"
 	"os/signal"
 	"strings"
 	"syscall"
@@ -15,29 +14,6 @@ import (
 	"screenshot-tool/screenshot"
 )
 
-// cleanupDockerContainer stops the chrome docker container if it was started by this app
-func cleanupDockerContainer() {
-	// Check if docker is installed
-	if _, err := exec.LookPath("docker"); err != nil {
-		return
-	}
-
-	// Check if chrome container is running
-	cmd := exec.Command("docker", "ps", "-q", "-f", "name=chrome", "-f", "status=running")
-	output, err := cmd.Output()
-	if err != nil || len(output) == 0 {
-		return
-	}
-
-	log.Println("Stopping Chrome Docker container...")
-	cmd = exec.Command("docker", "stop", "chrome")
-	if err := cmd.Run(); err != nil {
-		log.Printf("Failed to stop Chrome container: %v", err)
-		return
-	}
-	log.Println("Chrome Docker container stopped")
-}
-
 // extractDomain extracts a domain name from a URL for use as a default name
 func extractDomain(url string) string {
 	// Remove protocol if present
@@ -67,12 +43,19 @@ func extractDomain(url string) string {
 
 func main() {
 	// Parse command line flags
-	configPath := flag.String("config", "config.json", "Path to configuration file")
+	configPath := flag.String("config", "config.json", "Path to configuration file, a directory of .json files or a glob pattern to merge")
 	cmdUrls := flag.String("urls", "", "Comma-separated list of URLs to capture (overrides config file URLs)")
 	cmdUrl := flag.String("url", "", "Single URL to capture (overrides config file URLs)")
 	name := flag.String("name", "", "Name for the URL when using -url flag (defaults to domain)")
 	delay := flag.Int("delay", 0, "Delay in milliseconds for page loading when using -url flag (defaults to 1000)")
 	chromeMode := flag.String("chrome", "auto", "Chrome execution mode: 'local', 'docker', or 'auto'")
+	include := flag.String("include", "", "Regex; only capture URLs whose name or URL matches (overrides config includePattern)")
+	exclude := flag.String("exclude", "", "Regex; skip URLs whose name or URL matches (overrides config excludePattern)")
+	headed := flag.Bool("headed", false, "Show the Chrome window for debugging (local Chrome only)")
+	watch := flag.Bool("watch", false, "Re-capture on the configured watchIntervalSec until interrupted, keeping only changed images")
+	serve := flag.String("serve", "", "Address to serve on-demand screenshots over HTTP (e.g. :8080) instead of capturing the configured URLs")
+	selfCheck := flag.Bool("selfcheck", false, "Check that Chrome can be started and capture a screenshot of about:blank, then exit (non-zero on failure)")
+	tags := flag.String("tags", "", "Comma-separated list of tags; only capture URLs with at least one of them (overrides config runTags)")
 	flag.Parse()
 
 	// Validate chrome mode flag
@@ -81,7 +64,12 @@ func main() {
 	}
 
 	// Load configuration
-	cfg, err := config.LoadConfig(*configPath)
+	// Serve mode takes its URLs from requests, so the config does not need to list any
+	loadConfig := config.LoadConfig
+	if *serve != "" {
+		loadConfig = config.LoadServeConfig
+	}
+	cfg, err := loadConfig(*configPath)
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
@@ -90,6 +78,43 @@ func main() {
 	cfg.ChromeMode = *chromeMode
 	log.Printf("Using Chrome mode: %s", cfg.ChromeMode)
 
+	if *headed {
+		cfg.Headless = false
+	}
+	if !cfg.Headless && cfg.ChromeMode == "docker" {
+		log.Printf("Warning: headed mode only works with local Chrome; Docker Chrome always runs headless")
+	}
+
+	// The self-check uses the config's Chrome settings but none of its URLs
+	if *selfCheck {
+		if err := screenshot.SelfCheck(context.Background(), cfg); err != nil {
+			log.Fatalf("ERROR: %v", err)
+		}
+		return
+	}
+
+	// Serve mode captures the URLs in its requests rather than the configured ones
+	if *serve != "" {
+		runServe(cfg, *serve)
+		return
+	}
+
+	// Override URL filters from command line
+	if *include != "" {
+		cfg.IncludePattern = *include
+	}
+	if *exclude != "" {
+		cfg.ExcludePattern = *exclude
+	}
+	if *tags != "" {
+		cfg.RunTags = nil
+		for _, tag := range strings.Split(*tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				cfg.RunTags = append(cfg.RunTags, tag)
+			}
+		}
+	}
+
 	// Handle command-line URLs if provided
 	if *cmdUrl != "" || *cmdUrls != "" {
 		// Override config URLs with command line URLs
@@ -116,10 +141,11 @@ func main() {
 			}
 
 			cfg.URLs = append(cfg.URLs, config.URLConfig{
-				Name:      urlName,
-				URL:       *cmdUrl,
-				Viewports: viewports,
-				Delay:     urlDelay,
+				Name:         urlName,
+				URL:          *cmdUrl,
+				Viewports:    viewports,
+				Delay:        urlDelay,
+				WaitStrategy: cfg.WaitStrategy,
 			})
 
 			log.Printf("Using single URL from command line: %s", *cmdUrl)
@@ -145,10 +171,11 @@ func main() {
 				}
 
 				cfg.URLs = append(cfg.URLs, config.URLConfig{
-					Name:      extractDomain(url),
-					URL:       url,
-					Viewports: viewports,
-					Delay:     urlDelay,
+					Name:         extractDomain(url),
+					URL:          url,
+					Viewports:    viewports,
+					Delay:        urlDelay,
+					WaitStrategy: cfg.WaitStrategy,
 				})
 			}
 
@@ -175,20 +202,32 @@ func main() {
 		sig := <-signalChan
 		log.Printf("Received signal: %v, shutting down gracefully", sig)
 		cancel()
-		cleanupDockerContainer()
+		if err := screenshoter.Close(); err != nil {
+			log.Printf("Cleanup failed: %v", err)
+		}
 		// Allow some time for cleanup then exit if it takes too long
 		time.Sleep(5 * time.Second)
 		os.Exit(1)
 	}()
 
+	// Let operators pause a long run (SIGUSR1) and resume it (SIGUSR2)
+	handlePauseSignals(ctx, screenshoter)
+
 	// Run screenshot capture
 	log.Printf("Starting screenshot capture for %d URLs", len(cfg.URLs))
 	startTime := time.Now()
 
 	// Capture screenshots
-	if err := screenshoter.CaptureURLs(ctx); err != nil {
+	if *watch {
+		log.Printf("Watch mode: capturing every %d seconds", cfg.WatchIntervalSec)
+		if err := screenshoter.Watch(ctx); err != nil {
+			log.Printf("Watch mode failed: %v", err)
+		}
+	} else if err := screenshoter.CaptureURLs(ctx); err != nil {
 		log.Printf("Screenshot capture failed: %v", err)
-		cleanupDockerContainer()
+		if err := screenshoter.Close(); err != nil {
+			log.Printf("Cleanup failed: %v", err)
+		}
 		os.Exit(1)
 	}
 
@@ -197,5 +236,22 @@ func main() {
 	log.Printf("Screenshot capture completed successfully in %v", elapsed)
 
 	// Cleanup
-	cleanupDockerContainer()
+	if err := screenshoter.Close(); err != nil {
+		log.Printf("Cleanup failed: %v", err)
+	}
+}
+
+// runServe serves on-demand screenshots on addr until interrupted, then shuts down gracefully
+func runServe(cfg *config.Config, addr string) {
+	screenshoter := screenshot.NewScreenshoter(cfg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := screenshoter.Serve(ctx, addr); err != nil {
+		log.Printf("Serve mode failed: %v", err)
+	}
+	if err := screenshoter.Close(); err != nil {
+		log.Printf("Cleanup failed: %v", err)
+	}
 }
@@ -0,0 +1,35 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"screenshot-tool/screenshot"
+)
+
+// handlePauseSignals pauses the run on SIGUSR1 and resumes it on SIGUSR2 until ctx is done
+func handlePauseSignals(ctx context.Context, screenshoter *screenshot.Screenshoter) {
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		defer signal.Stop(signalChan)
+		for {
+			select {
+			case sig := <-signalChan:
+				log.Printf("Received signal: %v", sig)
+				if sig == syscall.SIGUSR1 {
+					screenshoter.Pause()
+				} else {
+					screenshoter.Resume()
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
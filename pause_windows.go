@@ -0,0 +1,10 @@
+package main
+
+import (
+	"context"
+
+	"screenshot-tool/screenshot"
+)
+
+// handlePauseSignals does nothing on Windows, which has no SIGUSR1 and SIGUSR2
+func handlePauseSignals(ctx context.Context, screenshoter *screenshot.Screenshoter) {}
@@ -0,0 +1,125 @@
+package screenshot
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// zipRun packages the directories of the latest run and its manifest into
+// <outputDir>/<run start timestamp>.zip, streaming each file into the archive.
+// With ZipOnly the loose files are removed once the archive has been verified.
+// Directories outside outputDir (a URL's absolute outputDir) are left as they are.
+func (s *Screenshoter) zipRun() (string, error) {
+	var dirs []string
+	for _, entry := range s.Manifest.URLs {
+		entryDirs := entry.Dirs
+		if entry.Dir != "" {
+			entryDirs = append([]string{entry.Dir}, entryDirs...)
+		}
+		for _, dir := range entryDirs {
+			if rel, err := filepath.Rel(s.Config.OutputDir, dir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				log.Printf("Not archiving %s, it is outside %s", dir, s.Config.OutputDir)
+				continue
+			}
+			dirs = append(dirs, dir)
+		}
+	}
+	manifestPath := filepath.Join(s.Config.OutputDir, "manifest.json")
+
+	// Runs started in the same millisecond still get their own archive
+	file, err := createUniqueFile(s.Config.OutputDir, s.Manifest.StartedAt.Format(fileTimestampLayout), ".zip")
+	if err != nil {
+		return "", fmt.Errorf("failed to create archive: %w", err)
+	}
+	zipPath := file.Name()
+
+	archive := zip.NewWriter(file)
+	addFile := func(path string) error {
+		rel, err := filepath.Rel(s.Config.OutputDir, path)
+		if err != nil {
+			return err
+		}
+		w, err := archive.Create(filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(w, src)
+		return err
+	}
+
+	for _, dir := range dirs {
+		err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			return addFile(path)
+		})
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = addFile(manifestPath)
+	}
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(zipPath)
+		return "", fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	entries, err := verifyZip(zipPath)
+	if err != nil {
+		return "", fmt.Errorf("archive %s is invalid: %w", zipPath, err)
+	}
+	log.Printf("Archived %d files to %s", entries, zipPath)
+
+	if s.Config.ZipOnly {
+		for _, dir := range dirs {
+			if err := os.RemoveAll(dir); err != nil {
+				log.Printf("Warning: failed to remove %s after archiving: %v", dir, err)
+			}
+		}
+		if err := os.Remove(manifestPath); err != nil {
+			log.Printf("Warning: failed to remove %s after archiving: %v", manifestPath, err)
+		}
+	}
+
+	return zipPath, nil
+}
+
+// verifyZip reads every entry of an archive back, which checks its CRC, and returns the entry count
+func verifyZip(path string) (int, error) {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	for _, entry := range reader.File {
+		rc, err := entry.Open()
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", entry.Name, err)
+		}
+		_, err = io.Copy(io.Discard, rc)
+		rc.Close()
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", entry.Name, err)
+		}
+	}
+	return len(reader.File), nil
+}
@@ -0,0 +1,142 @@
+package screenshot
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"screenshot-tool/config"
+)
+
+// writeTestFile writes content to path, creating its parent directories
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestZipRun(t *testing.T) {
+	for _, zipOnly := range []bool{false, true} {
+		outputDir := t.TempDir()
+		outside := t.TempDir()
+
+		files := map[string]string{
+			"home_20250101-120000/1280x800-full.png":    "home full",
+			"home_20250101-120000/375x667/viewport.png": "home mobile",
+			"1280x800/about_20250101-120000/full.png":   "about full",
+			"manifest.json": `{"urls":[]}`,
+		}
+		for name, content := range files {
+			writeTestFile(t, filepath.Join(outputDir, name), content)
+		}
+		writeTestFile(t, filepath.Join(outside, "external.png"), "external")
+		writeTestFile(t, filepath.Join(outputDir, "unrelated.txt"), "not part of the run")
+
+		s := &Screenshoter{
+			Config: &config.Config{OutputDir: outputDir, ZipOnly: zipOnly},
+			Manifest: &Manifest{
+				StartedAt: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+				URLs: []ManifestEntry{
+					{Name: "home", Dir: filepath.Join(outputDir, "home_20250101-120000")},
+					{Name: "about", Dirs: []string{filepath.Join(outputDir, "1280x800", "about_20250101-120000")}},
+					{Name: "external", Dir: outside},
+				},
+			},
+		}
+
+		zipPath, err := s.zipRun()
+		if err != nil {
+			t.Fatalf("zipOnly=%v: %v", zipOnly, err)
+		}
+		if want := filepath.Join(outputDir, "20250101-120000.000.zip"); zipPath != want {
+			t.Errorf("zipOnly=%v: archive at %s, want %s", zipOnly, zipPath, want)
+		}
+
+		reader, err := zip.OpenReader(zipPath)
+		if err != nil {
+			t.Fatalf("zipOnly=%v: %v", zipOnly, err)
+		}
+		got := make(map[string]string)
+		for _, entry := range reader.File {
+			rc, err := entry.Open()
+			if err != nil {
+				t.Fatalf("zipOnly=%v: %s: %v", zipOnly, entry.Name, err)
+			}
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatalf("zipOnly=%v: %s: %v", zipOnly, entry.Name, err)
+			}
+			got[entry.Name] = string(data)
+		}
+		reader.Close()
+
+		if len(got) != len(files) {
+			t.Errorf("zipOnly=%v: archive has %d entries, want %d: %v", zipOnly, len(got), len(files), got)
+		}
+		for name, content := range files {
+			if got[name] != content {
+				t.Errorf("zipOnly=%v: entry %s = %q, want %q", zipOnly, name, got[name], content)
+			}
+		}
+
+		// Loose files of the run are removed only with zipOnly; anything else stays
+		for name := range files {
+			_, err := os.Stat(filepath.Join(outputDir, name))
+			if zipOnly && !os.IsNotExist(err) {
+				t.Errorf("zipOnly: %s was not removed", name)
+			} else if !zipOnly && err != nil {
+				t.Errorf("%s was removed without zipOnly: %v", name, err)
+			}
+		}
+		for _, path := range []string{filepath.Join(outside, "external.png"), filepath.Join(outputDir, "unrelated.txt")} {
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("zipOnly=%v: %s was removed: %v", zipOnly, path, err)
+			}
+		}
+	}
+}
+
+func TestZipRunSameStart(t *testing.T) {
+	outputDir := t.TempDir()
+	writeTestFile(t, filepath.Join(outputDir, "home_20250101-120000", "full.png"), "home full")
+	writeTestFile(t, filepath.Join(outputDir, "manifest.json"), `{"urls":[]}`)
+
+	// Two runs started at the same instant must not overwrite each other's archive
+	startedAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	var paths []string
+	for i := 0; i < 2; i++ {
+		s := &Screenshoter{
+			Config: &config.Config{OutputDir: outputDir},
+			Manifest: &Manifest{
+				StartedAt: startedAt,
+				URLs:      []ManifestEntry{{Name: "home", Dir: filepath.Join(outputDir, "home_20250101-120000")}},
+			},
+		}
+		zipPath, err := s.zipRun()
+		if err != nil {
+			t.Fatalf("run %d: %v", i+1, err)
+		}
+		paths = append(paths, zipPath)
+	}
+
+	want := []string{
+		filepath.Join(outputDir, "20250101-120000.000.zip"),
+		filepath.Join(outputDir, "20250101-120000.000-2.zip"),
+	}
+	for i, path := range paths {
+		if path != want[i] {
+			t.Errorf("run %d: archive at %s, want %s", i+1, path, want[i])
+		}
+		if _, err := verifyZip(path); err != nil {
+			t.Errorf("run %d: %s is invalid: %v", i+1, path, err)
+		}
+	}
+}
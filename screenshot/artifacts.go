@@ -0,0 +1,149 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/cdproto/accessibility"
+	"github.com/chromedp/chromedp"
+)
+
+// inlineStylesHTMLScript returns the page's HTML with every element's computed style inlined.
+// Styles are copied onto a clone so the live page is left untouched.
+const inlineStylesHTMLScript = `
+(function() {
+	var source = [document.documentElement].concat(Array.prototype.slice.call(document.documentElement.querySelectorAll('*')));
+	var clone = document.documentElement.cloneNode(true);
+	var target = [clone].concat(Array.prototype.slice.call(clone.querySelectorAll('*')));
+	for (var i = 0; i < source.length && i < target.length; i++) {
+		var computed = window.getComputedStyle(source[i]);
+		var css = '';
+		for (var j = 0; j < computed.length; j++) {
+			var prop = computed[j];
+			css += prop + ':' + computed.getPropertyValue(prop) + ';';
+		}
+		target[i].setAttribute('style', css);
+	}
+	return clone.outerHTML;
+})()`
+
+// axNode is the trimmed-down accessibility node written to the AX tree file
+type axNode struct {
+	ID          string          `json:"id"`
+	ParentID    string          `json:"parentId,omitempty"`
+	Role        json.RawMessage `json:"role,omitempty"`
+	Name        json.RawMessage `json:"name,omitempty"`
+	Description json.RawMessage `json:"description,omitempty"`
+	Value       json.RawMessage `json:"value,omitempty"`
+	Ignored     bool            `json:"ignored,omitempty"`
+	ChildIDs    []string        `json:"childIds,omitempty"`
+}
+
+// axValue returns the raw JSON of an accessibility value, or nil when it is unset
+func axValue(value *accessibility.Value) json.RawMessage {
+	if value == nil || len(value.Value) == 0 {
+		return nil
+	}
+	return json.RawMessage(value.Value)
+}
+
+// saveArtifacts writes the configured non-image artifacts for the page currently loaded in the
+// tab, after all screenshots for the viewport have been taken. har is nil unless CaptureHAR is set.
+func (s *Screenshoter) saveArtifacts(ctx context.Context, urlConfig config.URLConfig, viewportDir string, har *harRecorder) error {
+	if s.Config.CaptureAccessibilityTree {
+		if err := s.saveAccessibilityTree(ctx, urlConfig, viewportDir); err != nil {
+			return err
+		}
+	}
+	if s.Config.SaveHTML {
+		if err := s.saveHTML(ctx, urlConfig, viewportDir); err != nil {
+			return err
+		}
+	}
+	if s.Config.CaptureSEO {
+		if err := s.saveSEO(ctx, urlConfig, viewportDir); err != nil {
+			return err
+		}
+	}
+	if s.Config.CapturePDF {
+		if err := s.savePDF(ctx, urlConfig, viewportDir); err != nil {
+			return err
+		}
+	}
+	if har != nil {
+		if err := har.writeHAR(s.Sink, urlConfig, viewportDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveHTML writes the page's current markup as <name>.html, optionally with computed styles inlined
+func (s *Screenshoter) saveHTML(ctx context.Context, urlConfig config.URLConfig, viewportDir string) error {
+	var html string
+	var err error
+	if s.Config.InlineComputedStyles {
+		err = chromedp.Evaluate(inlineStylesHTMLScript, &html).Do(ctx)
+	} else {
+		err = chromedp.OuterHTML("html", &html, chromedp.ByQuery).Do(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get page HTML: %w", err)
+	}
+
+	path := filepath.Join(viewportDir, sanitizeFilename(urlConfig.Name)+".html")
+	if err := s.Sink.Write(path, []byte("<!DOCTYPE html>\n"+html)); err != nil {
+		return fmt.Errorf("failed to write HTML: %w", err)
+	}
+
+	log.Printf("Saved HTML for %s: %s", urlConfig.Name, path)
+	return nil
+}
+
+// saveAccessibilityTree writes the full accessibility tree of the page as <name>-axtree.json
+func (s *Screenshoter) saveAccessibilityTree(ctx context.Context, urlConfig config.URLConfig, viewportDir string) error {
+	if err := accessibility.Enable().Do(ctx); err != nil {
+		return fmt.Errorf("failed to enable accessibility domain: %w", err)
+	}
+	defer accessibility.Disable().Do(ctx)
+
+	nodes, err := accessibility.GetFullAXTree().Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get accessibility tree: %w", err)
+	}
+
+	tree := make([]axNode, 0, len(nodes))
+	for _, node := range nodes {
+		entry := axNode{
+			ID:          string(node.NodeID),
+			ParentID:    string(node.ParentID),
+			Role:        axValue(node.Role),
+			Name:        axValue(node.Name),
+			Description: axValue(node.Description),
+			Value:       axValue(node.Value),
+			Ignored:     node.Ignored,
+		}
+		for _, childID := range node.ChildIDs {
+			entry.ChildIDs = append(entry.ChildIDs, string(childID))
+		}
+		tree = append(tree, entry)
+	}
+
+	data, err := json.MarshalIndent(tree, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode accessibility tree: %w", err)
+	}
+
+	path := filepath.Join(viewportDir, sanitizeFilename(urlConfig.Name)+"-axtree.json")
+	if err := s.Sink.Write(path, data); err != nil {
+		return fmt.Errorf("failed to write accessibility tree: %w", err)
+	}
+
+	log.Printf("Saved accessibility tree for %s (%d nodes): %s", urlConfig.Name, len(tree), path)
+	return nil
+}
@@ -0,0 +1,83 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/chromedp"
+)
+
+// auditPage loads the URL and records the cookies it sets in the cookie log and CSV, plus its
+// localStorage when AuditLocalStorage is set, without taking any screenshots. Configured cookies
+// and storage are applied as for a capture, so the log shows the page's state before and after.
+func (s *Screenshoter) auditPage(ctx context.Context, urlConfig config.URLConfig, viewport config.Viewport, viewportDir string) error {
+	timeout := time.Duration(s.Config.NavigationTimeoutMs) * time.Millisecond
+
+	tasks := []chromedp.Action{
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			navCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			if err := s.navigateTo(urlConfig.URL).Do(navCtx); err != nil {
+				return fmt.Errorf("navigation to %s failed: %w", urlConfig.URL, err)
+			}
+			return nil
+		}),
+		s.saveCookies(ctx, urlConfig, "before", viewportDir, viewport, "audit"),
+	}
+	if len(urlConfig.Cookies) > 0 || len(urlConfig.LocalStorage) > 0 {
+		tasks = append(tasks,
+			s.setCookiesAndLocalStorage(ctx, urlConfig, viewport, viewportDir, "after", "audit"),
+			s.reload(),
+		)
+	}
+	tasks = append(tasks,
+		s.waitForPage(urlConfig),
+		s.saveCookies(ctx, urlConfig, "loaded", viewportDir, viewport, "audit"),
+	)
+	if s.Config.AuditLocalStorage {
+		tasks = append(tasks, s.saveLocalStorage(urlConfig, viewportDir))
+	}
+
+	if err := chromedp.Run(ctx, s.slowMo(tasks)...); err != nil {
+		return err
+	}
+	log.Printf("Audited %s at viewport %dx%d", urlConfig.Name, viewport.Width, viewport.Height)
+	return nil
+}
+
+// saveLocalStorage writes the page's localStorage entries as <name>-localstorage.json
+func (s *Screenshoter) saveLocalStorage(urlConfig config.URLConfig, viewportDir string) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		storage, err := readStorage(ctx)
+		if err != nil {
+			return err
+		}
+		if storage.LocalStorage.Error != "" {
+			log.Printf("Warning: localStorage is not accessible on %s: %s", urlConfig.Name, storage.LocalStorage.Error)
+			return nil
+		}
+		entries := make(map[string]string, len(storage.LocalStorage.Entries))
+		for _, entry := range storage.LocalStorage.Entries {
+			entries[entry[0]] = entry[1]
+		}
+
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode localStorage: %w", err)
+		}
+
+		path := filepath.Join(viewportDir, sanitizeFilename(urlConfig.Name)+"-localstorage.json")
+		if err := s.Sink.Write(path, data); err != nil {
+			return fmt.Errorf("failed to write localStorage: %w", err)
+		}
+
+		log.Printf("Saved %d localStorage entries for %s: %s", len(entries), urlConfig.Name, path)
+		return nil
+	})
+}
@@ -0,0 +1,142 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+)
+
+// sharedBrowser is the Chrome kept running across CaptureURLs calls when
+// Config.KeepBrowserAlive is set, until Close
+type sharedBrowser struct {
+	browserCtx context.Context
+	cancel     func() // Shuts down the browser and its allocator
+	remote     bool
+}
+
+// openTab returns a tab for a capture at viewport: the first tab of a browser started for it,
+// or with KeepBrowserAlive a new tab of the shared browser. With IsolateContexts the tab gets a
+// browser context of its own. The returned function closes the tab, and its browser unless
+// shared; a shared browser's tab also closes as soon as ctx is done. The returned bool reports
+// whether the browser is Docker Chrome.
+func (s *Screenshoter) openTab(ctx context.Context, viewport config.Viewport) (context.Context, func(), bool, error) {
+	tabOptions := []chromedp.ContextOption{chromedp.WithLogf(log.Printf)}
+
+	if !s.Config.KeepBrowserAlive {
+		allocCtx, cancelAlloc, remote, err := s.newAllocator(ctx, viewport)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		browserCtx, cancelBrowser := chromedp.NewContext(allocCtx, tabOptions...)
+		closeBrowser := func() {
+			cancelBrowser()
+			cancelAlloc()
+		}
+		if !s.Config.IsolateContexts {
+			return browserCtx, closeBrowser, remote, nil
+		}
+
+		// Capture in a throwaway incognito-like browser context so cookies and storage cannot
+		// bleed in from other URLs sharing the same Chrome
+		if err := chromedp.Run(browserCtx); err != nil {
+			closeBrowser()
+			return nil, nil, false, fmt.Errorf("failed to start browser: %w", err)
+		}
+		tabCtx, cancelTab := chromedp.NewContext(browserCtx, chromedp.WithNewBrowserContext())
+		return tabCtx, func() {
+			cancelTab()
+			closeBrowser()
+		}, remote, nil
+	}
+
+	shared, err := s.sharedBrowser(viewport)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if s.Config.IsolateContexts {
+		tabOptions = append(tabOptions, chromedp.WithNewBrowserContext())
+	}
+	tabCtx, cancelTab := chromedp.NewContext(shared.browserCtx, tabOptions...)
+	stop := context.AfterFunc(ctx, cancelTab)
+	closeTab := func() {
+		stop()
+		cancelTab()
+	}
+	if err := s.sizeSharedTab(tabCtx, viewport); err != nil {
+		closeTab()
+		return nil, nil, false, err
+	}
+	return tabCtx, closeTab, shared.remote, nil
+}
+
+// sizeSharedTab emulates viewport in a tab of the shared browser before its first navigation.
+// The shared window keeps the size of the viewport the browser was started for, so without it
+// pages would load, lazy-load and report their height at that width. Tabs of a browser started
+// for their own viewport already have its size.
+func (s *Screenshoter) sizeSharedTab(ctx context.Context, viewport config.Viewport) error {
+	if !s.Config.KeepBrowserAlive {
+		return nil
+	}
+	err := chromedp.Run(ctx, emulation.SetDeviceMetricsOverride(int64(viewport.Width), int64(viewport.Height), 1, false))
+	if err != nil {
+		return fmt.Errorf("failed to size tab for viewport %s: %w", viewport, err)
+	}
+	return nil
+}
+
+// sharedBrowser returns the browser kept alive across runs, starting it on first use or after
+// it went away. Its window is sized for the viewport it was started for; each tab emulates its
+// own viewport size from the start (see sizeSharedTab).
+func (s *Screenshoter) sharedBrowser(viewport config.Viewport) (*sharedBrowser, error) {
+	s.sharedMu.Lock()
+	defer s.sharedMu.Unlock()
+	if s.shared != nil && s.shared.browserCtx.Err() == nil {
+		return s.shared, nil
+	}
+	if s.shared != nil {
+		log.Printf("Kept-alive browser is gone, starting a new one")
+		s.shared.cancel()
+		s.shared = nil
+	}
+
+	// The browser outlives the run that starts it, so it is not tied to any run's context
+	start := time.Now()
+	allocCtx, cancelAlloc, remote, err := s.newAllocator(context.Background(), viewport)
+	if err != nil {
+		return nil, err
+	}
+	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx, chromedp.WithLogf(log.Printf))
+	if err := chromedp.Run(browserCtx); err != nil {
+		cancelBrowser()
+		cancelAlloc()
+		return nil, fmt.Errorf("failed to start browser: %w", err)
+	}
+	log.Printf("Started browser in %v, keeping it alive for later captures", time.Since(start).Round(time.Millisecond))
+
+	s.shared = &sharedBrowser{
+		browserCtx: browserCtx,
+		cancel: func() {
+			cancelBrowser()
+			cancelAlloc()
+		},
+		remote: remote,
+	}
+	return s.shared, nil
+}
+
+// closeSharedBrowser shuts down the browser kept alive across runs, if any, so the next
+// capture starts a new one
+func (s *Screenshoter) closeSharedBrowser() {
+	s.sharedMu.Lock()
+	defer s.sharedMu.Unlock()
+	if s.shared != nil {
+		s.shared.cancel()
+		s.shared = nil
+	}
+}
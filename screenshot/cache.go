@@ -0,0 +1,192 @@
+package screenshot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/chromedp"
+)
+
+// captureCacheFile is the name of the content-hash cache kept in the output directory
+const captureCacheFile = "capture-cache.json"
+
+// cachedCapture is what the last run captured for one URL and viewport
+type cachedCapture struct {
+	DOMHash    string        `json:"domHash"` // SHA-256 of the settled page's outerHTML
+	CapturedAt time.Time     `json:"capturedAt"`
+	Files      []cachedImage `json:"files"`
+}
+
+// cachedImage pairs a recorded image with the SHA-256 of its contents
+type cachedImage struct {
+	ManifestFile
+	SHA256 string `json:"sha256"`
+}
+
+// captureCache remembers the DOM hash and images of each URL and viewport across runs so
+// unchanged pages can be skipped when Config.SkipUnchanged is set
+type captureCache struct {
+	mu      sync.Mutex
+	path    string
+	Entries map[string]*cachedCapture `json:"entries"` // Keyed by cacheKey
+}
+
+// cacheKey identifies a URL as one profile at one viewport and file format
+func cacheKey(urlConfig config.URLConfig, viewport config.Viewport, format string) string {
+	if urlConfig.ActiveProfile != "" {
+		return fmt.Sprintf("%s|%s|%s|%s", urlConfig.URL, urlConfig.ActiveProfile, viewport, format)
+	}
+	return fmt.Sprintf("%s|%s|%s", urlConfig.URL, viewport, format)
+}
+
+// loadCaptureCache reads the cache from outputDir, starting empty when there is none
+func loadCaptureCache(outputDir string) (*captureCache, error) {
+	cache := &captureCache{
+		path:    filepath.Join(outputDir, captureCacheFile),
+		Entries: make(map[string]*cachedCapture),
+	}
+
+	data, err := os.ReadFile(cache.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read capture cache: %w", err)
+	}
+	if err := json.Unmarshal(data, cache); err != nil {
+		return nil, fmt.Errorf("failed to parse capture cache %s: %w", cache.path, err)
+	}
+	if cache.Entries == nil {
+		cache.Entries = make(map[string]*cachedCapture)
+	}
+	return cache, nil
+}
+
+// save writes the cache back to the output directory
+func (c *captureCache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode capture cache: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write capture cache: %w", err)
+	}
+	return nil
+}
+
+// lookup returns the last capture with the given DOM hash whose images are all still on disk
+// with the recorded contents
+func (c *captureCache) lookup(key, domHash string) (*cachedCapture, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.Entries[key]
+	if !ok || entry.DOMHash != domHash || len(entry.Files) == 0 {
+		return nil, false
+	}
+	for _, file := range entry.Files {
+		if sum, err := fileSHA256(file.Path); err != nil || sum != file.SHA256 {
+			return nil, false
+		}
+	}
+	return entry, true
+}
+
+// store records the images just captured for key
+func (c *captureCache) store(key, domHash string, files []ManifestFile) {
+	images := make([]cachedImage, 0, len(files))
+	for _, file := range files {
+		sum, err := fileSHA256(file.Path)
+		if err != nil {
+			log.Printf("Warning: not caching %s: %v", file.Path, err)
+			return
+		}
+		images = append(images, cachedImage{ManifestFile: file, SHA256: sum})
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Entries[key] = &cachedCapture{DOMHash: domHash, CapturedAt: time.Now(), Files: images}
+}
+
+// fileSHA256 returns the hex SHA-256 of the file at path
+func fileSHA256(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// pageHash loads the URL the way the captures do and returns the SHA-256 of the settled
+// page's outerHTML, a quick stand-in for whether the page changed since the last run
+func (s *Screenshoter) pageHash(ctx context.Context, urlConfig config.URLConfig, viewport config.Viewport, viewportDir string) (string, error) {
+	tasks := []chromedp.Action{s.navigate(urlConfig, viewport, viewportDir, "full")}
+	if len(urlConfig.Cookies) > 0 || len(urlConfig.LocalStorage) > 0 {
+		tasks = append(tasks, s.setCookiesAndLocalStorage(ctx, urlConfig, viewport, viewportDir, "after", "page hash"), s.reload())
+	}
+	tasks = append(tasks, s.waitForPage(urlConfig))
+	tasks = append(tasks, s.waitBeforeCapture(urlConfig, viewport)...)
+
+	var html string
+	tasks = append(tasks, chromedp.Evaluate(`document.documentElement.outerHTML`, &html))
+	if err := chromedp.Run(ctx, s.slowMo(tasks)...); err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(html))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// skipIfUnchanged checks the page against the capture cache. When the DOM hash matches the last
+// run, the previous images are recorded in the manifest as unchanged and true is returned.
+// Otherwise the returned function stores this run's images once the captures have succeeded.
+func (s *Screenshoter) skipIfUnchanged(ctx context.Context, urlConfig config.URLConfig, viewport config.Viewport, viewportDir string) (bool, func(), error) {
+	noop := func() {}
+	if !s.Config.SkipUnchanged || s.cache == nil {
+		return false, noop, nil
+	}
+
+	domHash, err := s.pageHash(ctx, urlConfig, viewport, viewportDir)
+	if err != nil {
+		return false, noop, fmt.Errorf("failed to hash page: %w", err)
+	}
+
+	key := cacheKey(urlConfig, viewport, s.format(urlConfig))
+	if entry, ok := s.cache.lookup(key, domHash); ok {
+		log.Printf("Skipping %s at viewport %dx%d: page unchanged since %s",
+			urlConfig.Name, viewport.Width, viewport.Height, entry.CapturedAt.Format(time.RFC3339))
+		for _, image := range entry.Files {
+			file := image.ManifestFile
+			file.Unchanged = true
+			s.Manifest.addFile(file)
+		}
+		// The viewport directory was created for this run and stays empty
+		if err := os.Remove(viewportDir); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Warning: failed to remove empty directory %s: %v", viewportDir, err)
+		}
+		return true, noop, nil
+	}
+
+	return false, func() {
+		// A likely blank render should not stand in for later runs
+		if s.Manifest.hasSuspectFiles(viewportDir) {
+			return
+		}
+		s.cache.store(key, domHash, s.Manifest.filesIn(viewportDir))
+	}, nil
+}
@@ -0,0 +1,42 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+)
+
+// clearBrowserState removes cookies and web storage left over from earlier captures in the
+// browser, so the URL's configured cookies and localStorage are applied to a clean slate.
+// sessionStorage is per tab and every viewport gets a fresh tab, so it is always empty.
+func (s *Screenshoter) clearBrowserState(urlConfig config.URLConfig) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if urlConfig.ClearCookies {
+			if err := storage.ClearCookies().WithBrowserContextID(browserContextID(ctx)).Do(ctx); err != nil {
+				return fmt.Errorf("failed to clear cookies: %w", err)
+			}
+			log.Printf("Cleared cookies before capturing %s", urlConfig.Name)
+		}
+
+		if urlConfig.ClearStorage {
+			u, err := url.Parse(urlConfig.URL)
+			if err != nil || u.Host == "" {
+				return fmt.Errorf("cannot determine origin of %s to clear storage", urlConfig.URL)
+			}
+			origin := u.Scheme + "://" + u.Host
+			storageTypes := string(storage.TypeLocalStorage) + "," + string(storage.TypeIndexeddb)
+			if err := storage.ClearDataForOrigin(origin, storageTypes).Do(ctx); err != nil {
+				return fmt.Errorf("failed to clear storage for %s: %w", origin, err)
+			}
+			log.Printf("Cleared localStorage and IndexedDB of %s before capturing %s", origin, urlConfig.Name)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,111 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/chromedp"
+)
+
+// consentTextPrefix marks a ConsentSelectors entry matched against button and link text
+// instead of used as a CSS selector
+const consentTextPrefix = "text="
+
+// defaultConsentSelectors covers the accept buttons of widespread consent platforms, then
+// common button labels
+var defaultConsentSelectors = []string{
+	"#onetrust-accept-btn-handler",                           // OneTrust
+	"#CybotCookiebotDialogBodyLevelButtonLevelOptinAllowAll", // Cookiebot
+	"#CybotCookiebotDialogBodyButtonAccept",                  // Cookiebot (older)
+	"#didomi-notice-agree-button",                            // Didomi
+	".fc-cta-consent",                                        // Google Funding Choices
+	"#truste-consent-button",                                 // TrustArc
+	".qc-cmp2-summary-buttons button[mode='primary']",        // Quantcast
+	"#axeptio_btn_acceptAll",                                 // Axeptio
+	".cc-allow",                                              // Osano cookieconsent
+	".cc-dismiss",                                            // Osano cookieconsent (inform only)
+	"text=Accept all cookies",
+	"text=Accept all",
+	"text=Allow all cookies",
+	"text=Allow all",
+	"text=Accept cookies",
+	"text=I agree",
+	"text=Agree",
+	"text=Accept",
+	"text=Got it",
+}
+
+// consentClickScript clicks the first visible element matching one of the entries, trying
+// them in order, and returns the entry that matched or "" when none did
+const consentClickScript = `
+(function(entries) {
+	function visible(el) {
+		var rect = el.getBoundingClientRect();
+		var style = window.getComputedStyle(el);
+		return rect.width > 0 && rect.height > 0 && style.visibility !== 'hidden' && style.display !== 'none';
+	}
+	for (var i = 0; i < entries.length; i++) {
+		var entry = entries[i];
+		var candidates = [];
+		if (entry.indexOf('text=') === 0) {
+			var text = entry.slice(5).trim().toLowerCase();
+			var buttons = document.querySelectorAll('button, a, [role="button"], input[type="button"], input[type="submit"]');
+			for (var j = 0; j < buttons.length; j++) {
+				var label = (buttons[j].innerText || buttons[j].value || '').trim().toLowerCase();
+				if (label === text) {
+					candidates.push(buttons[j]);
+				}
+			}
+		} else {
+			try {
+				candidates = document.querySelectorAll(entry);
+			} catch (e) {
+				continue;
+			}
+		}
+		for (var k = 0; k < candidates.length; k++) {
+			if (visible(candidates[k])) {
+				candidates[k].click();
+				return entry;
+			}
+		}
+	}
+	return '';
+})(%s)`
+
+// acceptConsent clicks the first matching consent-accept button on the page, trying
+// Config.ConsentSelectors (or the built-in list) in order. Finding none is logged, not an error.
+func (s *Screenshoter) acceptConsent(urlConfig config.URLConfig) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		entries := s.Config.ConsentSelectors
+		if len(entries) == 0 {
+			entries = defaultConsentSelectors
+		}
+		encoded, err := json.Marshal(entries)
+		if err != nil {
+			return fmt.Errorf("failed to encode consent selectors: %w", err)
+		}
+
+		var matched string
+		if err := chromedp.Evaluate(fmt.Sprintf(consentClickScript, encoded), &matched).Do(ctx); err != nil {
+			return fmt.Errorf("failed to look for a consent banner: %w", err)
+		}
+		if matched == "" {
+			log.Printf("No consent banner found on %s", urlConfig.Name)
+			return nil
+		}
+
+		if strings.HasPrefix(matched, consentTextPrefix) {
+			log.Printf("Accepted consent on %s by clicking the button labelled %q", urlConfig.Name, strings.TrimPrefix(matched, consentTextPrefix))
+		} else {
+			log.Printf("Accepted consent on %s by clicking %s", urlConfig.Name, matched)
+		}
+		// Let the banner close and the page settle
+		return s.scrollSettle().Do(ctx)
+	})
+}
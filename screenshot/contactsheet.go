@@ -0,0 +1,184 @@
+package screenshot
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"log"
+	"path/filepath"
+
+	"screenshot-tool/config"
+)
+
+// Contact sheet layout, in pixels
+const (
+	contactSheetTileWidth     = 400  // Wider captures are scaled down to this width
+	contactSheetMaxTileHeight = 1600 // Taller tiles are cut off at the bottom
+	contactSheetPadding       = 16
+	contactSheetLabelScale    = 3 // Size of a label font pixel
+)
+
+// contactSheetTypes are the capture types a tile is taken from, in order of preference
+var contactSheetTypes = []string{"full", "hero", "viewport"}
+
+var (
+	contactSheetBackground = color.RGBA{0xf2, 0xf2, 0xf2, 0xff}
+	contactSheetLabelColor = color.RGBA{0x33, 0x33, 0x33, 0xff}
+)
+
+// labelFont is a 5x7 bitmap font covering the characters of viewport names ("1280x800",
+// "1280x800-zoom87.5"); each row's low five bits are its pixels, left to right
+var labelFont = map[rune][7]uint8{
+	'0': {0x0e, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0e},
+	'1': {0x04, 0x0c, 0x04, 0x04, 0x04, 0x04, 0x0e},
+	'2': {0x0e, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1f},
+	'3': {0x1f, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0e},
+	'4': {0x02, 0x06, 0x0a, 0x12, 0x1f, 0x02, 0x02},
+	'5': {0x1f, 0x10, 0x1e, 0x01, 0x01, 0x11, 0x0e},
+	'6': {0x06, 0x08, 0x10, 0x1e, 0x11, 0x11, 0x0e},
+	'7': {0x1f, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8': {0x0e, 0x11, 0x11, 0x0e, 0x11, 0x11, 0x0e},
+	'9': {0x0e, 0x11, 0x11, 0x0f, 0x01, 0x02, 0x0c},
+	'x': {0x00, 0x00, 0x11, 0x0a, 0x04, 0x0a, 0x11},
+	'-': {0x00, 0x00, 0x00, 0x1f, 0x00, 0x00, 0x00},
+	'.': {0x00, 0x00, 0x00, 0x00, 0x00, 0x0c, 0x0c},
+	'z': {0x00, 0x00, 0x1f, 0x02, 0x04, 0x08, 0x1f},
+	'o': {0x00, 0x00, 0x0e, 0x11, 0x11, 0x11, 0x0e},
+	'm': {0x00, 0x00, 0x1a, 0x15, 0x15, 0x11, 0x11},
+}
+
+// labelHeight is the height of a label drawn by drawLabel
+const labelHeight = 7 * contactSheetLabelScale
+
+// drawLabel draws text onto img with its top left corner at (x, y). Characters missing from
+// labelFont are left blank.
+func drawLabel(img *image.RGBA, x, y int, text string) {
+	for _, r := range text {
+		glyph := labelFont[r]
+		for row, bits := range glyph {
+			for col := 0; col < 5; col++ {
+				if bits&(1<<(4-col)) == 0 {
+					continue
+				}
+				px := image.Rect(x+col*contactSheetLabelScale, y+row*contactSheetLabelScale,
+					x+(col+1)*contactSheetLabelScale, y+(row+1)*contactSheetLabelScale)
+				draw.Draw(img, px, image.NewUniform(contactSheetLabelColor), image.Point{}, draw.Src)
+			}
+		}
+		x += 6 * contactSheetLabelScale
+	}
+}
+
+// contactSheetTile is one viewport's image on a contact sheet
+type contactSheetTile struct {
+	label string
+	img   image.Image
+}
+
+// writeContactSheet composes the captures of urlConfig at each of its viewports side by side,
+// each labelled with its viewport, and writes the result as <base>-contactsheet.<fmt> in dir.
+// Each tile is the full page capture, or the hero or first section when there is none.
+// Viewports without a capture are left out.
+func (s *Screenshoter) writeContactSheet(urlConfig config.URLConfig, dir, base string) error {
+	var tiles []contactSheetTile
+	for _, viewport := range urlConfig.Viewports {
+		file, ok := s.contactSheetSource(urlConfig, viewport)
+		if !ok {
+			continue
+		}
+		data, err := s.readCapture(file.Path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file.Path, err)
+		}
+		img, err := decodeImage(data)
+		if err != nil {
+			return err
+		}
+		tiles = append(tiles, contactSheetTile{label: viewport.String(), img: contactSheetTileImage(img)})
+	}
+	if len(tiles) == 0 {
+		log.Printf("No captures of %s to put on a contact sheet", urlConfig.Name)
+		return nil
+	}
+
+	width, height := contactSheetPadding, 0
+	for _, tile := range tiles {
+		width += tile.img.Bounds().Dx() + contactSheetPadding
+		height = max(height, tile.img.Bounds().Dy())
+	}
+	height += labelHeight + 3*contactSheetPadding
+
+	sheet := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(sheet, sheet.Bounds(), image.NewUniform(contactSheetBackground), image.Point{}, draw.Src)
+	x := contactSheetPadding
+	for _, tile := range tiles {
+		drawLabel(sheet, x, contactSheetPadding, tile.label)
+		top := labelHeight + 2*contactSheetPadding
+		bounds := tile.img.Bounds()
+		draw.Draw(sheet, image.Rect(x, top, x+bounds.Dx(), top+bounds.Dy()), tile.img, bounds.Min, draw.Src)
+		x += bounds.Dx() + contactSheetPadding
+	}
+
+	format := s.format(urlConfig)
+	var out []byte
+	var err error
+	if format == "jpeg" {
+		out, err = encodeJPEG(sheet, s.Config.Quality, s.Config.ProgressiveJPEG)
+	} else {
+		out, err = encodePNG(sheet, pngCompressionLevels[s.Config.PNGCompression])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode contact sheet: %w", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-contactsheet.%s", base, format))
+	if err := s.Sink.Write(path, out); err != nil {
+		return err
+	}
+	s.Manifest.addFile(ManifestFile{
+		Name:    urlConfig.Name,
+		Profile: urlConfig.ActiveProfile,
+		Type:    "contactsheet",
+		Path:    path,
+		Bytes:   len(out),
+	})
+	log.Printf("Saved contact sheet of %d viewports for %s: %s", len(tiles), urlConfig.Name, path)
+	return nil
+}
+
+// contactSheetSource returns the image of urlConfig at viewport recorded in this run's manifest
+// that best represents it on a contact sheet
+func (s *Screenshoter) contactSheetSource(urlConfig config.URLConfig, viewport config.Viewport) (ManifestFile, bool) {
+	s.Manifest.mu.Lock()
+	defer s.Manifest.mu.Unlock()
+	for _, kind := range contactSheetTypes {
+		for _, file := range s.Manifest.Files {
+			if file.Name == urlConfig.Name && file.Profile == urlConfig.ActiveProfile &&
+				file.Viewport == viewport.String() && file.Type == kind {
+				return file, true
+			}
+		}
+	}
+	return ManifestFile{}, false
+}
+
+// contactSheetTileImage scales img down to the tile width and cuts it off at the maximum
+// tile height
+func contactSheetTileImage(img image.Image) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > contactSheetTileWidth {
+		// Only the part that stays on the sheet is scaled
+		visible := min(height, contactSheetMaxTileHeight*width/contactSheetTileWidth)
+		cropped := image.NewRGBA(image.Rect(0, 0, width, visible))
+		draw.Draw(cropped, cropped.Bounds(), img, bounds.Min, draw.Src)
+		return downscale(cropped, contactSheetTileWidth, max(1, visible*contactSheetTileWidth/width))
+	}
+	if height > contactSheetMaxTileHeight {
+		cropped := image.NewRGBA(image.Rect(0, 0, width, contactSheetMaxTileHeight))
+		draw.Draw(cropped, cropped.Bounds(), img, bounds.Min, draw.Src)
+		return cropped
+	}
+	return img
+}
@@ -0,0 +1,42 @@
+package screenshot
+
+import (
+	"context"
+	"log"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// handleDialogs answers every JavaScript dialog the page opens in the tab of ctx according to
+// Config.DialogAction, so an alert or confirm on load cannot stall the capture until the URL
+// times out. beforeunload prompts are always accepted, since dismissing one would cancel the
+// tool's own navigation.
+func (s *Screenshoter) handleDialogs(ctx context.Context, urlConfig config.URLConfig) {
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		opening, ok := ev.(*page.EventJavascriptDialogOpening)
+		if !ok {
+			return
+		}
+
+		accept := s.Config.DialogAction == config.DialogAccept || opening.Type == page.DialogTypeBeforeunload
+		action := "Dismissed"
+		if accept {
+			action = "Accepted"
+		}
+		log.Printf("%s %s dialog on %s: %q", action, opening.Type, urlConfig.Name, opening.Message)
+
+		// Listeners must not block, and the dialog has to be answered through the tab itself
+		go func() {
+			handle := page.HandleJavaScriptDialog(accept)
+			if accept && opening.Type == page.DialogTypePrompt {
+				handle = handle.WithPromptText(opening.DefaultPrompt)
+			}
+			if err := chromedp.Run(ctx, handle); err != nil && ctx.Err() == nil {
+				log.Printf("Warning: failed to answer %s dialog on %s: %v", opening.Type, urlConfig.Name, err)
+			}
+		}()
+	})
+}
@@ -0,0 +1,195 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// ElementBox records which EachSelector match an image shows and where it sits on the page
+type ElementBox struct {
+	Index  int     `json:"index"` // 1-based, among the visible matches
+	Count  int     `json:"count"` // Visible matches captured on the page
+	X      float64 `json:"x"`     // Page coordinates in CSS pixels
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// elementBoxFunction returns the page box and visibility of the element it is called on. It
+// runs on the node itself, so it works inside closed shadow roots as well.
+const elementBoxFunction = `function() {
+	var rect = this.getBoundingClientRect();
+	var style = window.getComputedStyle(this);
+	return {
+		visible: rect.width > 0 && rect.height > 0 && style.visibility !== 'hidden' && style.display !== 'none',
+		x: rect.left + window.scrollX,
+		y: rect.top + window.scrollY,
+		width: rect.width,
+		height: rect.height
+	};
+}`
+
+// elementMatch is one result of elementBoxFunction
+type elementMatch struct {
+	Visible bool    `json:"visible"`
+	X       float64 `json:"x"`
+	Y       float64 `json:"y"`
+	Width   float64 `json:"width"`
+	Height  float64 `json:"height"`
+}
+
+// captureEachSelector captures every visible element matching URLConfig.EachSelector into its
+// own numbered image, recording each one's index, the count and its box in the manifest.
+// Invisible matches are skipped.
+func (s *Screenshoter) captureEachSelector(ctx context.Context, urlConfig config.URLConfig, viewport config.Viewport, viewportDir string) error {
+	timestamp := fileTimestamp()
+
+	steps, err := config.SplitShadowSelector(urlConfig.EachSelector)
+	if err != nil {
+		return err
+	}
+
+	var nodes []*cdp.Node
+	tasks := []chromedp.Action{s.navigate(urlConfig, viewport, viewportDir, "element")}
+	if len(urlConfig.Cookies) > 0 || len(urlConfig.LocalStorage) > 0 {
+		tasks = append(tasks, s.setCookiesAndLocalStorage(ctx, urlConfig, viewport, viewportDir, "after", "element"), s.reload())
+	}
+	tasks = append(tasks, s.waitForPage(urlConfig))
+	tasks = append(tasks, s.lazyLoadScroll(urlConfig, viewportDir)...)
+	tasks = append(tasks, s.waitBeforeCapture(urlConfig, viewport)...)
+	if len(steps) == 1 {
+		tasks = append(tasks, chromedp.Nodes(urlConfig.EachSelector, &nodes, chromedp.ByQueryAll, chromedp.AtLeast(0)))
+	} else {
+		tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			nodes, err = queryThroughShadowRoots(ctx, steps)
+			return err
+		}))
+	}
+	if err := chromedp.Run(ctx, s.slowMo(tasks)...); err != nil {
+		return err
+	}
+
+	matches := make([]elementMatch, len(nodes))
+	var visible []int
+	for i, node := range nodes {
+		if err := chromedp.Run(ctx, elementBox(node, &matches[i])); err != nil {
+			return fmt.Errorf("failed to measure match %d of %q: %w", i+1, urlConfig.EachSelector, err)
+		}
+		if matches[i].Visible {
+			visible = append(visible, i)
+		}
+	}
+	log.Printf("eachSelector %q matched %d elements on %s, %d visible", urlConfig.EachSelector, len(nodes), urlConfig.Name, len(visible))
+
+	for n, i := range visible {
+		var buf []byte
+		if err := chromedp.Run(ctx, chromedp.ScreenshotNodes([]*cdp.Node{nodes[i]}, 1, &buf)); err != nil {
+			return fmt.Errorf("failed to capture element %d of %q: %w", n+1, urlConfig.EachSelector, err)
+		}
+
+		filename := fmt.Sprintf("%s-element-%dx%d-%d.%s", timestamp, viewport.Width, viewport.Height, n+1, s.format(urlConfig))
+		path, err := s.writeImage(urlConfig, viewport, "element", filepath.Join(viewportDir, filename), buf)
+		if err != nil {
+			return err
+		}
+		box := &ElementBox{
+			Index:  n + 1,
+			Count:  len(visible),
+			X:      matches[i].X,
+			Y:      matches[i].Y,
+			Width:  matches[i].Width,
+			Height: matches[i].Height,
+		}
+		s.Manifest.updateFile(path, func(file *ManifestFile) { file.Element = box })
+	}
+
+	log.Printf("Captured %d element screenshots for %s", len(visible), urlConfig.Name)
+	return nil
+}
+
+// elementBox measures node with elementBoxFunction into match
+func elementBox(node *cdp.Node, match *elementMatch) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		object, err := dom.ResolveNode().WithNodeID(node.NodeID).Do(ctx)
+		if err != nil {
+			return err
+		}
+		// Releasing fails harmlessly once the page has navigated away
+		defer func() { _ = runtime.ReleaseObject(object.ObjectID).Do(ctx) }()
+		return chromedp.CallFunctionOn(elementBoxFunction, match, func(p *runtime.CallFunctionOnParams) *runtime.CallFunctionOnParams {
+			return p.WithObjectID(object.ObjectID)
+		}).Do(ctx)
+	})
+}
+
+// queryThroughShadowRoots returns the elements matching the last of steps, in document order.
+// The first step is matched in the document and each later one inside the shadow roots of the
+// previous step's matches. DOM.getDocument with pierce reaches open and closed shadow roots
+// alike; a matched host without any shadow root is an error.
+func queryThroughShadowRoots(ctx context.Context, steps []string) ([]*cdp.Node, error) {
+	document, err := dom.GetDocument().WithDepth(-1).WithPierce(true).Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the document: %w", err)
+	}
+	byID := make(map[cdp.NodeID]*cdp.Node)
+	var index func(node *cdp.Node)
+	index = func(node *cdp.Node) {
+		byID[node.NodeID] = node
+		for _, child := range node.Children {
+			index(child)
+		}
+		for _, root := range node.ShadowRoots {
+			index(root)
+		}
+	}
+	index(document)
+
+	scopes := []*cdp.Node{document}
+	for i, step := range steps {
+		var matches []*cdp.Node
+		for _, scope := range scopes {
+			ids, err := dom.QuerySelectorAll(scope.NodeID, step).Do(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to query %q: %w", step, err)
+			}
+			for _, id := range ids {
+				node, ok := byID[id]
+				if !ok {
+					node = &cdp.Node{NodeID: id}
+				}
+				matches = append(matches, node)
+			}
+		}
+		if i == len(steps)-1 {
+			return matches, nil
+		}
+
+		scopes = nil
+		for _, host := range matches {
+			var roots []*cdp.Node
+			for _, root := range host.ShadowRoots {
+				// Built-in controls such as <input> have user-agent roots, which are not the page's
+				if root.ShadowRootType != cdp.ShadowRootTypeUserAgent {
+					roots = append(roots, root)
+				}
+			}
+			if len(roots) == 0 {
+				return nil, fmt.Errorf("%q matched <%s>, which has no shadow root to look for %q in",
+					step, strings.ToLower(host.NodeName), steps[i+1])
+			}
+			scopes = append(scopes, roots...)
+		}
+	}
+	return nil, nil
+}
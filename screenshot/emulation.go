@@ -0,0 +1,304 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// emulateMedia applies the URL's CSS media type and feature overrides to the tab. Overrides
+// persist across navigations, so this runs once before any capture in the tab. It reports
+// whether anything was emulated so the caller can reset the tab afterwards.
+func (s *Screenshoter) emulateMedia(ctx context.Context, urlConfig config.URLConfig) (bool, error) {
+	if len(urlConfig.MediaFeatures) == 0 && !urlConfig.EmulatePrintMedia {
+		return false, nil
+	}
+
+	params := emulation.SetEmulatedMedia()
+	if urlConfig.EmulatePrintMedia {
+		log.Printf("Emulating print media for %s", urlConfig.Name)
+		params = params.WithMedia("print")
+	}
+
+	names := make([]string, 0, len(urlConfig.MediaFeatures))
+	for name := range urlConfig.MediaFeatures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	features := make([]*emulation.MediaFeature, 0, len(names))
+	for _, name := range names {
+		features = append(features, &emulation.MediaFeature{Name: name, Value: urlConfig.MediaFeatures[name]})
+		log.Printf("Emulating media feature for %s: %s: %s", urlConfig.Name, name, urlConfig.MediaFeatures[name])
+	}
+	if len(features) > 0 {
+		params = params.WithFeatures(features)
+	}
+
+	if err := chromedp.Run(ctx, params); err != nil {
+		return false, fmt.Errorf("failed to emulate media: %w", err)
+	}
+	return true, nil
+}
+
+// resetMedia restores screen media and clears feature overrides so a reused tab renders normally
+func resetMedia(ctx context.Context) {
+	if err := chromedp.Run(ctx, emulation.SetEmulatedMedia().WithMedia("").WithFeatures([]*emulation.MediaFeature{})); err != nil {
+		log.Printf("Warning: failed to reset media emulation: %v", err)
+	}
+}
+
+// zoomScript applies a CSS zoom to the root element of every document loaded in the tab, as
+// soon as the element exists, so the page lays out the way it does under browser zoom
+const zoomScript = `
+(function() {
+	var zoom = '%s';
+	function apply() {
+		if (!document.documentElement) {
+			return false;
+		}
+		document.documentElement.style.setProperty('zoom', zoom, 'important');
+		return true;
+	}
+	if (!apply()) {
+		new MutationObserver(function(records, observer) {
+			if (apply()) {
+				observer.disconnect();
+			}
+		}).observe(document, {childList: true});
+	}
+})()`
+
+// emulateZoom installs the viewport's zoom factor for every navigation in the tab. Unlike a
+// device scale factor, which only changes pixel density, zoom changes the CSS layout width.
+func (s *Screenshoter) emulateZoom(ctx context.Context, viewport config.Viewport) error {
+	if viewport.Zoom == 0 || viewport.Zoom == 1 {
+		return nil
+	}
+
+	log.Printf("Emulating %v%% zoom at viewport %dx%d", viewport.Zoom*100, viewport.Width, viewport.Height)
+	script := fmt.Sprintf(zoomScript, strconv.FormatFloat(viewport.Zoom, 'f', -1, 64))
+	return addInitScript(ctx, script)
+}
+
+// touchViewportWidth is the width below which ForceMediaMatch reports a touch device
+const touchViewportWidth = 1024
+
+// matchMediaShim replaces window.matchMedia with one that answers width, height, device-width,
+// device-height, aspect-ratio, orientation, pointer and hover queries from the configured
+// viewport. Queries using anything else are passed to the browser's own matchMedia.
+const matchMediaShim = `
+(function() {
+	var state = {width: %d, height: %d, touch: %t};
+	var original = window.matchMedia ? window.matchMedia.bind(window) : null;
+
+	function length(value) {
+		var match = /^([0-9.]+)(px|em|rem)?$/.exec(value);
+		if (!match) {
+			return undefined;
+		}
+		var number = parseFloat(match[1]);
+		return match[2] === 'em' || match[2] === 'rem' ? number * 16 : number;
+	}
+
+	function ratio(value) {
+		var parts = value.split('/');
+		if (parts.length === 1) {
+			return parseFloat(parts[0]);
+		}
+		return parseFloat(parts[0]) / parseFloat(parts[1]);
+	}
+
+	function feature(name, value) {
+		var prefix = '';
+		var base = name;
+		if (name.indexOf('min-') === 0 || name.indexOf('max-') === 0) {
+			prefix = name.slice(0, 4);
+			base = name.slice(4);
+		}
+		var actual, expected;
+		switch (base) {
+		case 'width':
+		case 'device-width':
+			actual = state.width;
+			expected = length(value);
+			break;
+		case 'height':
+		case 'device-height':
+			actual = state.height;
+			expected = length(value);
+			break;
+		case 'aspect-ratio':
+		case 'device-aspect-ratio':
+			actual = state.width / state.height;
+			expected = ratio(value);
+			break;
+		case 'orientation':
+			return prefix === '' ? value === (state.height >= state.width ? 'portrait' : 'landscape') : undefined;
+		case 'pointer':
+		case 'any-pointer':
+			return prefix === '' ? value === (state.touch ? 'coarse' : 'fine') : undefined;
+		case 'hover':
+		case 'any-hover':
+			return prefix === '' ? value === (state.touch ? 'none' : 'hover') : undefined;
+		default:
+			return undefined;
+		}
+		if (expected === undefined || isNaN(expected)) {
+			return undefined;
+		}
+		if (prefix === 'min-') {
+			return actual >= expected;
+		}
+		if (prefix === 'max-') {
+			return actual <= expected;
+		}
+		return actual === expected;
+	}
+
+	function query(text) {
+		var negate = false;
+		var tokens = text.trim().toLowerCase();
+		if (tokens.indexOf('not ') === 0) {
+			negate = true;
+			tokens = tokens.slice(4);
+		} else if (tokens.indexOf('only ') === 0) {
+			tokens = tokens.slice(5);
+		}
+		var result = true;
+		var parts = tokens.split(/\s+and\s+/);
+		for (var i = 0; i < parts.length; i++) {
+			var part = parts[i].trim();
+			if (part === 'all' || part === 'screen') {
+				continue;
+			}
+			if (part === 'print') {
+				result = false;
+				continue;
+			}
+			var match = /^\(\s*([a-z-]+)\s*(?::\s*([^)]+?))?\s*\)$/.exec(part);
+			if (!match || match[2] === undefined) {
+				return undefined;
+			}
+			var matched = feature(match[1], match[2].trim());
+			if (matched === undefined) {
+				return undefined;
+			}
+			result = result && matched;
+		}
+		return negate ? !result : result;
+	}
+
+	function evaluate(media) {
+		var queries = media.split(',');
+		var matches = false;
+		for (var i = 0; i < queries.length; i++) {
+			var result = query(queries[i]);
+			if (result === undefined) {
+				return undefined;
+			}
+			matches = matches || result;
+		}
+		return matches;
+	}
+
+	window.matchMedia = function(media) {
+		var matches = evaluate(String(media));
+		if (matches === undefined && original) {
+			return original(media);
+		}
+		var listeners = [];
+		return {
+			matches: !!matches,
+			media: String(media),
+			onchange: null,
+			addListener: function(listener) { listeners.push(listener); },
+			removeListener: function(listener) { listeners = listeners.filter(function(l) { return l !== listener; }); },
+			addEventListener: function(type, listener) { if (type === 'change') { listeners.push(listener); } },
+			removeEventListener: function(type, listener) { listeners = listeners.filter(function(l) { return l !== listener; }); },
+			dispatchEvent: function() { return true; }
+		};
+	};
+})()`
+
+// forceMediaMatch installs the matchMedia shim for every document loaded in the tab, before
+// the page's own scripts run, so sites that pick their layout through matchMedia see the
+// configured viewport. Viewports narrower than touchViewportWidth report a touch device.
+func (s *Screenshoter) forceMediaMatch(ctx context.Context, urlConfig config.URLConfig, viewport config.Viewport) error {
+	if !urlConfig.ForceMediaMatch {
+		return nil
+	}
+
+	touch := viewport.Width < touchViewportWidth
+	log.Printf("Forcing matchMedia for %s to %dx%d (touch: %t)", urlConfig.Name, viewport.Width, viewport.Height, touch)
+	script := fmt.Sprintf(matchMediaShim, viewport.Width, viewport.Height, touch)
+	return addInitScript(ctx, script)
+}
+
+// fixedClockScript pins Date, Date.now and performance.now to a fixed instant. Date called
+// with arguments still builds that date, and instances pass instanceof Date checks. The real
+// Date.now is kept as window.__screenshotRealNow for the tool's own waits (see pageNowJS).
+const fixedClockScript = `
+(function() {
+	var fixed = %d;
+	var RealDate = Date;
+	var performanceNow = performance.now();
+	if (!window.__screenshotRealNow) {
+		window.__screenshotRealNow = RealDate.now.bind(RealDate);
+	}
+	function FixedDate() {
+		if (!new.target) {
+			return new RealDate(fixed).toString();
+		}
+		var args = arguments.length ? Array.prototype.slice.call(arguments) : [fixed];
+		return Reflect.construct(RealDate, args, new.target);
+	}
+	FixedDate.prototype = RealDate.prototype;
+	FixedDate.now = function() { return fixed; };
+	FixedDate.parse = RealDate.parse;
+	FixedDate.UTC = RealDate.UTC;
+	window.Date = FixedDate;
+	performance.now = function() { return performanceNow; };
+})()`
+
+// injectInitScripts registers the URL's FixedTime clock and InitScripts to run before any page
+// script in every document the tab loads, including reloads and the login page. The clock goes
+// first so init scripts see it too.
+func (s *Screenshoter) injectInitScripts(ctx context.Context, urlConfig config.URLConfig) error {
+	if urlConfig.FixedTime != "" {
+		fixed, err := time.Parse(time.RFC3339, urlConfig.FixedTime)
+		if err != nil {
+			return fmt.Errorf("invalid fixedTime: %w", err)
+		}
+		if err := addInitScript(ctx, fmt.Sprintf(fixedClockScript, fixed.UnixMilli())); err != nil {
+			return fmt.Errorf("fixed clock: %w", err)
+		}
+		log.Printf("Pinned the clock of %s to %s", urlConfig.Name, fixed.Format(time.RFC3339))
+	}
+	for i, script := range urlConfig.InitScripts {
+		if err := addInitScript(ctx, script); err != nil {
+			return fmt.Errorf("init script #%d: %w", i+1, err)
+		}
+	}
+	if len(urlConfig.InitScripts) > 0 {
+		log.Printf("Injected %d init scripts for %s", len(urlConfig.InitScripts), urlConfig.Name)
+	}
+	return nil
+}
+
+// addInitScript registers script to run in every new document of the tab before its own scripts
+func addInitScript(ctx context.Context, script string) error {
+	return chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		_, err := page.AddScriptToEvaluateOnNewDocument(script).Do(ctx)
+		return err
+	}))
+}
@@ -0,0 +1,110 @@
+package screenshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"screenshot-tool/config"
+)
+
+// Event types written to the event stream
+const (
+	EventCaptureStarted   = "capture_started"   // A viewport of a URL starts capturing; the viewports of a responsive sweep start together
+	EventCaptureSucceeded = "capture_succeeded" // A viewport finished, with the images it wrote
+	EventCaptureFailed    = "capture_failed"    // A viewport failed; after a failed responsive sweep it starts again on its own
+	EventURLDone          = "url_done"          // All viewports of a URL are done, with the URL's manifest status
+)
+
+// Event is a line of the event stream. Capture events describe one viewport of a URL and
+// profile; url_done sums up the URL.
+type Event struct {
+	Type       string    `json:"type"`
+	Time       time.Time `json:"time"`
+	Name       string    `json:"name"`
+	URL        string    `json:"url"`
+	Profile    string    `json:"profile,omitempty"`
+	Viewport   string    `json:"viewport,omitempty"`
+	Status     string    `json:"status,omitempty"`     // url_done: the URL's manifest status
+	DurationMs int64     `json:"durationMs,omitempty"` // Since the matching start: the viewport's capture or the whole URL
+	Images     int       `json:"images,omitempty"`     // Images written: by the viewport, or by the whole URL for url_done
+	Error      string    `json:"error,omitempty"`
+}
+
+// openEvents opens Config.EventsFile for appending, or stdout for "-", as the run's event
+// stream
+func (s *Screenshoter) openEvents() error {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+	if s.Config.EventsFile == "-" {
+		s.eventsFile = os.Stdout
+		return nil
+	}
+	file, err := os.OpenFile(s.Config.EventsFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open events file: %w", err)
+	}
+	s.eventsFile = file
+	return nil
+}
+
+// closeEvents closes the event stream opened by openEvents
+func (s *Screenshoter) closeEvents() {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+	if s.eventsFile != nil && s.eventsFile != os.Stdout {
+		if err := s.eventsFile.Close(); err != nil {
+			log.Printf("Warning: failed to close events file: %v", err)
+		}
+	}
+	s.eventsFile = nil
+}
+
+// emit writes ev, stamped with the current time, as a line of JSON to Events, or to the events
+// file of the current run. Without either it does nothing. A failed write is logged and does
+// not affect the capture.
+func (s *Screenshoter) emit(ev Event) {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+	var w io.Writer
+	if s.Events != nil {
+		w = s.Events
+	} else if s.eventsFile != nil {
+		w = s.eventsFile
+	} else {
+		return
+	}
+
+	ev.Time = time.Now()
+	data, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("Warning: failed to encode %s event: %v", ev.Type, err)
+		return
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		log.Printf("Warning: failed to write %s event: %v", ev.Type, err)
+	}
+}
+
+// emitCapture writes a capture event for urlConfig at viewport. Finished captures report the
+// time since start, the images now in viewportDir and err, if any.
+func (s *Screenshoter) emitCapture(eventType string, urlConfig config.URLConfig, viewport config.Viewport, viewportDir string, start time.Time, err error) {
+	ev := Event{
+		Type:     eventType,
+		Name:     urlConfig.Name,
+		URL:      urlConfig.URL,
+		Profile:  urlConfig.ActiveProfile,
+		Viewport: viewport.String(),
+	}
+	if eventType != EventCaptureStarted {
+		ev.DurationMs = time.Since(start).Milliseconds()
+		ev.Images = len(s.Manifest.filesIn(viewportDir))
+	}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	s.emit(ev)
+}
@@ -0,0 +1,71 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// filmstripDir is the subdirectory of a viewport directory holding filmstrip frames, kept apart
+// so early blank frames are not mistaken for failed captures
+const filmstripDir = "filmstrip"
+
+// captureFilmstrip navigates to the URL without waiting for it to load and captures the top
+// viewport every IntervalMs, writing numbered frames into the viewport's filmstrip directory
+func (s *Screenshoter) captureFilmstrip(ctx context.Context, urlConfig config.URLConfig, viewport config.Viewport, viewportDir string) error {
+	filmstrip := urlConfig.Filmstrip
+	dir := filepath.Join(viewportDir, filmstripDir)
+	if s.writesFiles() {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create filmstrip directory: %w", err)
+		}
+	}
+
+	interval := time.Duration(filmstrip.IntervalMs) * time.Millisecond
+	timestamp := fileTimestamp()
+
+	return chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		// page.Navigate returns once the response starts arriving, unlike chromedp.Navigate
+		// which waits for the load event
+		start := time.Now()
+		if _, _, errorText, err := page.Navigate(urlConfig.URL).Do(ctx); err != nil {
+			return err
+		} else if errorText != "" {
+			return fmt.Errorf("navigation to %s failed: %s", urlConfig.URL, errorText)
+		}
+
+		for frame := 1; frame <= filmstrip.Frames; frame++ {
+			// Frames are scheduled from navigation start; a slow capture delays only the next frame
+			if wait := time.Until(start.Add(time.Duration(frame-1) * interval)); wait > 0 {
+				if err := chromedp.Sleep(wait).Do(ctx); err != nil {
+					return err
+				}
+			}
+
+			offset := time.Since(start)
+			var buf []byte
+			if err := chromedp.CaptureScreenshot(&buf).Do(ctx); err != nil {
+				return fmt.Errorf("failed to capture filmstrip frame %d: %w", frame, err)
+			}
+
+			filename := fmt.Sprintf("%s-filmstrip-%dx%d-%03d.%s", timestamp, viewport.Width, viewport.Height, frame, s.format(urlConfig))
+			path, err := s.writeImage(urlConfig, viewport, "filmstrip", filepath.Join(dir, filename), buf)
+			if err != nil {
+				return err
+			}
+			s.Manifest.updateFile(path, func(file *ManifestFile) { file.OffsetMs = offset.Milliseconds() })
+		}
+
+		log.Printf("Captured %d filmstrip frames for %s at viewport %dx%d every %v",
+			filmstrip.Frames, urlConfig.Name, viewport.Width, viewport.Height, interval)
+		return nil
+	}))
+}
@@ -0,0 +1,79 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+)
+
+// frameWindowJS returns a JavaScript expression for the window that element lookups and
+// pre-capture scripts run in: the top-level window, or the content window of the URL's
+// FrameSelector. Accessing a cross-origin frame throws a descriptive error.
+func frameWindowJS(urlConfig config.URLConfig) string {
+	if urlConfig.FrameSelector == "" {
+		return "window"
+	}
+	return fmt.Sprintf(`(function() {
+		const frame = document.querySelector("%[1]s");
+		if (!frame) {
+			throw new Error('frame "%[1]s" not found');
+		}
+		if (!frame.contentWindow) {
+			throw new Error('element "%[1]s" is not a frame');
+		}
+		if (!frame.contentDocument) {
+			throw new Error('frame "%[1]s" is cross-origin; only same-origin frames can be scoped');
+		}
+		return frame.contentWindow;
+	})()`, escapeJSString(urlConfig.FrameSelector))
+}
+
+// inFrame wraps a JavaScript expression so it is evaluated in the URL's frame, if any
+func inFrame(urlConfig config.URLConfig, expression string) string {
+	if urlConfig.FrameSelector == "" {
+		return expression
+	}
+	return fmt.Sprintf(`%s.eval("%s")`, frameWindowJS(urlConfig), escapeJSString(expression))
+}
+
+// checkFrame resolves the URL's FrameSelector and fails early with a clear error when it
+// does not point at a frame, or points at a cross-origin frame the scripts cannot reach
+func checkFrame(urlConfig config.URLConfig) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var nodes []*cdp.Node
+		if err := chromedp.Nodes(urlConfig.FrameSelector, &nodes, chromedp.ByQuery, chromedp.AtLeast(0)).Do(ctx); err != nil {
+			return fmt.Errorf("failed to resolve frameSelector %q: %w", urlConfig.FrameSelector, err)
+		}
+		if len(nodes) == 0 {
+			return fmt.Errorf("frameSelector %q not found on %s", urlConfig.FrameSelector, urlConfig.URL)
+		}
+		if name := strings.ToUpper(nodes[0].NodeName); name != "IFRAME" && name != "FRAME" {
+			return fmt.Errorf("frameSelector %q matches a <%s>, not a frame", urlConfig.FrameSelector, strings.ToLower(name))
+		}
+
+		// Out-of-process frames and frames with a different origin are not scriptable from the page
+		var sameOrigin bool
+		script := fmt.Sprintf(`(function() {
+			try {
+				return !!document.querySelector("%s").contentDocument;
+			} catch (e) {
+				return false;
+			}
+		})()`, escapeJSString(urlConfig.FrameSelector))
+		if err := chromedp.Evaluate(script, &sameOrigin).Do(ctx); err != nil {
+			return fmt.Errorf("failed to inspect frameSelector %q: %w", urlConfig.FrameSelector, err)
+		}
+		if !sameOrigin {
+			return fmt.Errorf("frameSelector %q is a cross-origin frame; the DevTools protocol cannot scope scripts into it from the page, capture its URL directly instead", urlConfig.FrameSelector)
+		}
+
+		log.Printf("Scoping element capture and ready checks for %s to frame %q (frame ID %s)", urlConfig.Name, urlConfig.FrameSelector, nodes[0].FrameID)
+		return nil
+	})
+}
@@ -0,0 +1,365 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// HAR 1.2 structures, see http://www.softwareishard.com/blog/har-12-spec/
+type harLog struct {
+	Log harContent `json:"log"`
+}
+
+type harContent struct {
+	Version string      `json:"version"`
+	Creator harCreator  `json:"creator"`
+	Pages   []*harPage  `json:"pages"`
+	Entries []*harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harPage struct {
+	StartedDateTime time.Time      `json:"startedDateTime"`
+	ID              string         `json:"id"`
+	Title           string         `json:"title"`
+	PageTimings     harPageTimings `json:"pageTimings"`
+
+	start time.Time // Monotonic start used to compute page timings
+}
+
+type harPageTimings struct {
+	OnContentLoad float64 `json:"onContentLoad"`
+	OnLoad        float64 `json:"onLoad"`
+}
+
+type harEntry struct {
+	PageRef         string      `json:"pageref,omitempty"`
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Error           string      `json:"_error,omitempty"` // Custom field: why the request failed
+
+	start  time.Time               // Monotonic time the request was sent
+	timing *network.ResourceTiming // Timing reported with the response
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int64          `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harBody        `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harBody struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// harRecorder collects the network traffic of a tab, correlating events by request ID
+type harRecorder struct {
+	mu        sync.Mutex
+	pages     []*harPage
+	entries   []*harEntry
+	pending   map[network.RequestID]*harEntry
+	mainFrame cdp.FrameID
+}
+
+// startHAR enables the network domain in the tab and starts recording its traffic
+func startHAR(ctx context.Context) (*harRecorder, error) {
+	recorder := &harRecorder{pending: make(map[network.RequestID]*harEntry)}
+	chromedp.ListenTarget(ctx, recorder.handleEvent)
+	if err := chromedp.Run(ctx, network.Enable()); err != nil {
+		return nil, fmt.Errorf("failed to enable network domain: %w", err)
+	}
+	return recorder, nil
+}
+
+// handleEvent records a single network or page event
+func (r *harRecorder) handleEvent(ev interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		// A redirect reuses the request ID; close the previous hop with the redirect response
+		if previous, ok := r.pending[ev.RequestID]; ok && ev.RedirectResponse != nil {
+			previous.setResponse(ev.RedirectResponse)
+			previous.finish(ev.Timestamp, ev.RedirectResponse.EncodedDataLength)
+			delete(r.pending, ev.RequestID)
+		}
+		r.startEntry(ev)
+	case *network.EventResponseReceived:
+		if entry, ok := r.pending[ev.RequestID]; ok {
+			entry.setResponse(ev.Response)
+		}
+	case *network.EventLoadingFinished:
+		if entry, ok := r.pending[ev.RequestID]; ok {
+			entry.finish(ev.Timestamp, ev.EncodedDataLength)
+			delete(r.pending, ev.RequestID)
+		}
+	case *network.EventLoadingFailed:
+		if entry, ok := r.pending[ev.RequestID]; ok {
+			entry.Error = ev.ErrorText
+			entry.finish(ev.Timestamp, 0)
+			delete(r.pending, ev.RequestID)
+		}
+	case *page.EventDomContentEventFired:
+		if current := r.currentPage(); current != nil && ev.Timestamp != nil {
+			current.PageTimings.OnContentLoad = milliseconds(ev.Timestamp.Time().Sub(current.start))
+		}
+	case *page.EventLoadEventFired:
+		if current := r.currentPage(); current != nil && ev.Timestamp != nil {
+			current.PageTimings.OnLoad = milliseconds(ev.Timestamp.Time().Sub(current.start))
+		}
+	}
+}
+
+// startEntry opens an entry for a request, starting a new HAR page for top-level navigations
+func (r *harRecorder) startEntry(ev *network.EventRequestWillBeSent) {
+	if ev.Request == nil || ev.Timestamp == nil || ev.WallTime == nil {
+		return
+	}
+
+	// The first navigation in the tab is always the main frame
+	isNavigation := ev.Type == network.ResourceTypeDocument && string(ev.RequestID) == string(ev.LoaderID)
+	if isNavigation && r.mainFrame == "" {
+		r.mainFrame = ev.FrameID
+	}
+	if isNavigation && ev.FrameID == r.mainFrame && ev.RedirectResponse == nil {
+		r.pages = append(r.pages, &harPage{
+			StartedDateTime: ev.WallTime.Time(),
+			ID:              fmt.Sprintf("page_%d", len(r.pages)+1),
+			Title:           ev.Request.URL,
+			PageTimings:     harPageTimings{OnContentLoad: -1, OnLoad: -1},
+			start:           ev.Timestamp.Time(),
+		})
+	}
+
+	requestURL := ev.Request.URL + ev.Request.URLFragment
+	entry := &harEntry{
+		StartedDateTime: ev.WallTime.Time(),
+		Request: harRequest{
+			Method:      ev.Request.Method,
+			URL:         requestURL,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(ev.Request.Headers),
+			QueryString: harQueryString(requestURL),
+			HeadersSize: -1,
+			BodySize:    0,
+		},
+		Response: harResponse{
+			Cookies:     []harNameValue{},
+			Headers:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Timings: harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1},
+		start:   ev.Timestamp.Time(),
+	}
+	if ev.Request.HasPostData {
+		entry.Request.BodySize = -1
+	}
+	if current := r.currentPage(); current != nil {
+		entry.PageRef = current.ID
+	}
+
+	r.pending[ev.RequestID] = entry
+	r.entries = append(r.entries, entry)
+}
+
+// currentPage returns the page requests are currently attributed to
+func (r *harRecorder) currentPage() *harPage {
+	if len(r.pages) == 0 {
+		return nil
+	}
+	return r.pages[len(r.pages)-1]
+}
+
+// setResponse fills in the response part of an entry
+func (e *harEntry) setResponse(response *network.Response) {
+	e.Response.Status = response.Status
+	e.Response.StatusText = response.StatusText
+	e.Response.HTTPVersion = response.Protocol
+	e.Response.Headers = harHeaders(response.Headers)
+	e.Response.Content.MimeType = response.MimeType
+	if location, ok := response.Headers["Location"]; ok {
+		e.Response.RedirectURL = fmt.Sprint(location)
+	} else if location, ok := response.Headers["location"]; ok {
+		e.Response.RedirectURL = fmt.Sprint(location)
+	}
+	e.Request.HTTPVersion = response.Protocol
+	e.ServerIPAddress = response.RemoteIPAddress
+	e.timing = response.Timing
+}
+
+// finish records the total time and timing breakdown of an entry
+func (e *harEntry) finish(timestamp *cdp.MonotonicTime, encodedDataLength float64) {
+	if timestamp != nil {
+		e.Time = milliseconds(timestamp.Time().Sub(e.start))
+	}
+	e.Response.Content.Size = int(encodedDataLength)
+
+	timing := e.timing
+	if timing == nil {
+		e.Timings.Send = 0
+		e.Timings.Wait = e.Time
+		e.Timings.Receive = 0
+		return
+	}
+
+	span := func(start, end float64) float64 {
+		if start < 0 || end < 0 {
+			return -1
+		}
+		return end - start
+	}
+	e.Timings.DNS = span(timing.DNSStart, timing.DNSEnd)
+	e.Timings.Connect = span(timing.ConnectStart, timing.ConnectEnd)
+	e.Timings.SSL = span(timing.SslStart, timing.SslEnd)
+	e.Timings.Send = span(timing.SendStart, timing.SendEnd)
+	e.Timings.Wait = span(timing.SendEnd, timing.ReceiveHeadersEnd)
+	if firstActivity := timing.DNSStart; firstActivity >= 0 {
+		e.Timings.Blocked = firstActivity
+	} else if timing.SendStart >= 0 {
+		e.Timings.Blocked = timing.SendStart
+	}
+	if e.Timings.Send < 0 {
+		e.Timings.Send = 0
+	}
+	if e.Timings.Wait < 0 {
+		e.Timings.Wait = 0
+	}
+	if receive := e.Time - timing.ReceiveHeadersEnd; receive > 0 {
+		e.Timings.Receive = receive
+	}
+}
+
+// writeHAR writes everything recorded so far to <name>.har in the viewport directory
+func (r *harRecorder) writeHAR(sink Sink, urlConfig config.URLConfig, viewportDir string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	har := harLog{Log: harContent{
+		Version: "1.2",
+		Creator: harCreator{Name: "screenshot-tool", Version: "1.0"},
+		Pages:   r.pages,
+		Entries: r.entries,
+	}}
+	if har.Log.Pages == nil {
+		har.Log.Pages = []*harPage{}
+	}
+	if har.Log.Entries == nil {
+		har.Log.Entries = []*harEntry{}
+	}
+
+	data, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode HAR: %w", err)
+	}
+
+	path := filepath.Join(viewportDir, sanitizeFilename(urlConfig.Name)+".har")
+	if err := sink.Write(path, data); err != nil {
+		return fmt.Errorf("failed to write HAR: %w", err)
+	}
+
+	log.Printf("Saved HAR for %s (%d requests): %s", urlConfig.Name, len(r.entries), path)
+	return nil
+}
+
+// harHeaders converts CDP headers into sorted HAR name/value pairs. CDP joins repeated
+// headers with newlines, so those are split back into separate pairs.
+func harHeaders(headers network.Headers) []harNameValue {
+	pairs := []harNameValue{}
+	for name, value := range headers {
+		for _, line := range strings.Split(fmt.Sprint(value), "\n") {
+			pairs = append(pairs, harNameValue{Name: name, Value: line})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Name != pairs[j].Name {
+			return pairs[i].Name < pairs[j].Name
+		}
+		return pairs[i].Value < pairs[j].Value
+	})
+	return pairs
+}
+
+// harQueryString extracts the query parameters of a URL as HAR name/value pairs
+func harQueryString(rawURL string) []harNameValue {
+	pairs := []harNameValue{}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return pairs
+	}
+	values := u.Query()
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range values[name] {
+			pairs = append(pairs, harNameValue{Name: name, Value: value})
+		}
+	}
+	return pairs
+}
+
+// milliseconds converts a duration into fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
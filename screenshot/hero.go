@@ -0,0 +1,44 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+)
+
+// captureHero captures exactly the top viewport of a freshly loaded page as its own image,
+// before any ViewProof overlay touches the tab, so it can be used as a clean asset
+func (s *Screenshoter) captureHero(ctx context.Context, urlConfig config.URLConfig, viewport config.Viewport, viewportDir string) error {
+	var buf []byte
+	timestamp := fileTimestamp()
+	filename := fmt.Sprintf("%s-hero-%dx%d.%s", timestamp, viewport.Width, viewport.Height, s.format(urlConfig))
+
+	tasks := []chromedp.Action{s.navigate(urlConfig, viewport, viewportDir, "hero")}
+	if len(urlConfig.Cookies) > 0 || len(urlConfig.LocalStorage) > 0 {
+		tasks = append(tasks, s.setCookiesAndLocalStorage(ctx, urlConfig, viewport, viewportDir, "after", "hero"), s.reload())
+	}
+	tasks = append(tasks, s.waitForPage(urlConfig))
+	tasks = append(tasks, s.waitBeforeCapture(urlConfig, viewport)...)
+	tasks = append(tasks,
+		chromedp.Evaluate(`window.scrollTo(0, 0)`, nil),
+		emulation.SetDeviceMetricsOverride(int64(viewport.Width), int64(viewport.Height), 1, false),
+		s.scrollSettle(),
+		chromedp.CaptureScreenshot(&buf),
+	)
+	if err := chromedp.Run(ctx, s.slowMo(tasks)...); err != nil {
+		return err
+	}
+
+	path, err := s.writeImage(urlConfig, viewport, "hero", filepath.Join(viewportDir, filename), buf)
+	if err != nil {
+		return err
+	}
+	log.Printf("Captured hero screenshot for %s: %s", urlConfig.Name, path)
+	return nil
+}
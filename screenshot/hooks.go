@@ -0,0 +1,42 @@
+package screenshot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"time"
+
+	"screenshot-tool/config"
+)
+
+// runHookTimeout bounds a single run of Config.PreRunCommand or Config.PostRunCommand
+const runHookTimeout = 10 * time.Minute
+
+// runHook runs a pre- or post-run command, named by option in logs and errors, in the
+// working directory of this process, logging its output. An empty command does nothing.
+func runHook(ctx context.Context, option, command string) error {
+	if command == "" {
+		return nil
+	}
+	args, err := config.SplitCommand(command)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", option, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, runHookTimeout)
+	defer cancel()
+
+	log.Printf("Running %s: %s", option, command)
+	start := time.Now()
+	output, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	if output = bytes.TrimSpace(output); len(output) > 0 {
+		log.Printf("%s output:\n%s", option, output)
+	}
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", option, err)
+	}
+	log.Printf("%s finished in %v", option, time.Since(start).Round(time.Millisecond))
+	return nil
+}
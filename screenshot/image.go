@@ -0,0 +1,386 @@
+package screenshot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"screenshot-tool/config"
+)
+
+// errImageBudgetExceeded is returned once a run reaches Config.MaxImages
+var errImageBudgetExceeded = errors.New("image budget exceeded")
+
+// Quality floor and step used when shrinking images to fit MaxFileBytes
+const (
+	minShrinkQuality  = 10
+	shrinkQualityStep = 10
+)
+
+// pngCompressionLevels maps Config.PNGCompression values to encoder levels
+var pngCompressionLevels = map[string]png.CompressionLevel{
+	"default": png.DefaultCompression,
+	"none":    png.NoCompression,
+	"speed":   png.BestSpeed,
+	"best":    png.BestCompression,
+}
+
+// decodeImage decodes a captured screenshot buffer
+func decodeImage(buf []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(buf))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode captured image: %w", err)
+	}
+	return img, nil
+}
+
+// cropTop removes the top fraction of a captured screenshot, rounded to whole pixels, and
+// returns the rest as PNG
+func cropTop(buf []byte, fraction float64) ([]byte, error) {
+	img, err := decodeImage(buf)
+	if err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	top := int(math.Round(fraction * float64(bounds.Dy())))
+	if top <= 0 {
+		return buf, nil
+	}
+	if top >= bounds.Dy() {
+		return nil, fmt.Errorf("cropping %dpx leaves nothing of a %dpx high image", top, bounds.Dy())
+	}
+
+	cropped := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()-top))
+	draw.Draw(cropped, cropped.Bounds(), img, image.Pt(bounds.Min.X, bounds.Min.Y+top), draw.Src)
+	return encodePNG(cropped, png.DefaultCompression)
+}
+
+// encodeJPEG encodes an image as JPEG at the given quality, progressive or baseline
+func encodeJPEG(img image.Image, quality int, progressive bool) ([]byte, error) {
+	if progressive {
+		return encodeProgressiveJPEG(img, quality)
+	}
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, fmt.Errorf("failed to encode JPEG: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// encodePNG encodes an image as PNG with the given compression level
+func encodePNG(img image.Image, level png.CompressionLevel) ([]byte, error) {
+	var out bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: level}
+	if err := encoder.Encode(&out, img); err != nil {
+		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// shrinkToFit encodes img as JPEG with progressively lower quality until it fits in maxBytes.
+// It returns the smallest attempt and its quality even if the floor is reached without fitting.
+func shrinkToFit(img image.Image, maxBytes, quality int, progressive bool) ([]byte, int, error) {
+	if quality < minShrinkQuality {
+		quality = minShrinkQuality
+	}
+
+	var out []byte
+	for ; quality >= minShrinkQuality; quality -= shrinkQualityStep {
+		encoded, err := encodeJPEG(img, quality, progressive)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = encoded
+		if len(out) <= maxBytes {
+			return out, quality, nil
+		}
+	}
+	return out, quality + shrinkQualityStep, nil
+}
+
+// encodeImage re-encodes a captured screenshot in Go: JPEG output uses the given quality
+// (Chrome always hands back PNG) and PNG output is recompressed when PNGCompression is set.
+// The result is decoded again to verify it is valid and keeps the original dimensions.
+func (s *Screenshoter) encodeImage(img image.Image, buf []byte, format string, quality int) ([]byte, error) {
+	var out []byte
+	var err error
+	switch {
+	case format == "jpeg":
+		out, err = encodeJPEG(img, quality, s.Config.ProgressiveJPEG)
+	case s.Config.PNGCompression != "":
+		out, err = encodePNG(img, pngCompressionLevels[s.Config.PNGCompression])
+	default:
+		return buf, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	check, _, err := image.DecodeConfig(bytes.NewReader(out))
+	if err != nil {
+		return nil, fmt.Errorf("re-encoded image does not decode: %w", err)
+	}
+	bounds := img.Bounds()
+	if check.Width != bounds.Dx() || check.Height != bounds.Dy() {
+		return nil, fmt.Errorf("re-encoded image is %dx%d, expected %dx%d",
+			check.Width, check.Height, bounds.Dx(), bounds.Dy())
+	}
+
+	return out, nil
+}
+
+// sameImageFiles reports whether two image files decode to identical pixels
+func sameImageFiles(pathA, pathB string) (bool, error) {
+	bufA, err := os.ReadFile(pathA)
+	if err != nil {
+		return false, err
+	}
+	bufB, err := os.ReadFile(pathB)
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(bufA, bufB) {
+		return true, nil
+	}
+
+	imgA, err := decodeImage(bufA)
+	if err != nil {
+		return false, err
+	}
+	imgB, err := decodeImage(bufB)
+	if err != nil {
+		return false, err
+	}
+
+	bounds := imgA.Bounds()
+	if bounds != imgB.Bounds() {
+		return false, nil
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, a1 := imgA.At(x, y).RGBA()
+			r2, g2, b2, a2 := imgB.At(x, y).RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// processedImage is a screenshot after the configured post-capture processing
+type processedImage struct {
+	path    string // Output path, with the extension changed if the image was converted
+	data    []byte
+	quality int  // JPEG quality used, 0 for PNG
+	suspect bool // Below Config.MinContentRatio
+}
+
+// processImage applies the configured post-capture processing to a screenshot: re-encoding,
+// blank detection, embedding meta when it is not nil and shrinking to fit MaxFileBytes, which
+// counts the embedded metadata. The image is written as format, and JPEG output starts at
+// jpegQuality. path is only used for naming and logs.
+func (s *Screenshoter) processImage(path string, buf []byte, format string, jpegQuality int, meta *ImageMetadata) (processedImage, error) {
+	// Only pay for decoding when the image is going to be re-encoded
+	var img image.Image
+	var err error
+	if format == "jpeg" || s.Config.PNGCompression != "" || s.Config.MaxFileBytes > 0 || s.Config.MinContentRatio > 0 {
+		if img, err = decodeImage(buf); err != nil {
+			return processedImage{}, err
+		}
+	}
+
+	if buf, err = s.encodeImage(img, buf, format, jpegQuality); err != nil {
+		return processedImage{}, err
+	}
+
+	// Flag captures that are almost entirely background, usually a page that did not render
+	suspect := false
+	if s.Config.MinContentRatio > 0 {
+		background, _ := config.ParseHexColor(s.Config.BackgroundColor)
+		if ratio := contentRatio(img, background); ratio < s.Config.MinContentRatio {
+			log.Printf("Warning: %s is suspect, only %.2f%% of it differs from %s (minimum %.2f%%)",
+				filepath.Base(path), ratio*100, s.Config.BackgroundColor, s.Config.MinContentRatio*100)
+			suspect = true
+		}
+	}
+
+	quality := 0
+	if format == "jpeg" {
+		quality = jpegQuality
+	}
+
+	if meta != nil {
+		if buf, err = embedMetadata(buf, *meta); err != nil {
+			return processedImage{}, err
+		}
+	}
+
+	if s.Config.MaxFileBytes > 0 && len(buf) > s.Config.MaxFileBytes {
+		convert := format == "jpeg" || s.Config.ConvertOversizedPNG
+		if !convert {
+			log.Printf("Warning: %s is %d bytes, over the %d byte limit (PNG conversion disabled)",
+				filepath.Base(path), len(buf), s.Config.MaxFileBytes)
+		} else {
+			// JPEG output was already encoded at the configured quality, so start one step lower
+			startQuality := jpegQuality
+			if format == "jpeg" {
+				startQuality -= shrinkQualityStep
+			}
+			// Leave room for the metadata embedded into the shrunk JPEG
+			maxBytes := s.Config.MaxFileBytes
+			if meta != nil {
+				maxBytes -= metadataSize("jpeg", *meta)
+			}
+			shrunk, finalQuality, err := shrinkToFit(img, maxBytes, startQuality, s.Config.ProgressiveJPEG && format == "jpeg")
+			if err != nil {
+				return processedImage{}, err
+			}
+			if meta != nil {
+				if shrunk, err = embedMetadata(shrunk, *meta); err != nil {
+					return processedImage{}, err
+				}
+			}
+			if format != "jpeg" {
+				path = strings.TrimSuffix(path, filepath.Ext(path)) + ".jpeg"
+			}
+			if len(shrunk) > s.Config.MaxFileBytes {
+				log.Printf("Warning: %s is still %d bytes at minimum quality %d (limit %d)",
+					filepath.Base(path), len(shrunk), finalQuality, s.Config.MaxFileBytes)
+			} else {
+				log.Printf("Reduced %s from %d to %d bytes using quality %d",
+					filepath.Base(path), len(buf), len(shrunk), finalQuality)
+			}
+			buf = shrunk
+			quality = finalQuality
+		}
+	}
+
+	return processedImage{path: path, data: buf, quality: quality, suspect: suspect}, nil
+}
+
+// format returns the image format for captures of urlConfig: its own format when set,
+// otherwise Config.FileFormat
+func (s *Screenshoter) format(urlConfig config.URLConfig) string {
+	if urlConfig.FileFormat != "" {
+		return urlConfig.FileFormat
+	}
+	return s.Config.FileFormat
+}
+
+// quality returns the JPEG quality for captures at viewport: its own quality when set,
+// otherwise Config.Quality
+func (s *Screenshoter) quality(viewport config.Viewport) int {
+	if viewport.Quality > 0 {
+		return viewport.Quality
+	}
+	return s.Config.Quality
+}
+
+// writeImage processes a screenshot, writes it and records it in the manifest. It returns
+// the path actually written, which may differ from path when the image was converted to
+// another format.
+func (s *Screenshoter) writeImage(urlConfig config.URLConfig, viewport config.Viewport, kind, path string, buf []byte) (string, error) {
+	start := time.Now()
+	defer func() { s.timings.add(timingKey(urlConfig, viewport), phaseWrite, time.Since(start)) }()
+
+	viewportName := viewport.String()
+	var meta *ImageMetadata
+	if s.Config.EmbedMetadata {
+		meta = &ImageMetadata{
+			Name:       urlConfig.Name,
+			URL:        urlConfig.URL,
+			Viewport:   viewportName,
+			Type:       kind,
+			CapturedAt: time.Now().UTC().Truncate(time.Second),
+			Software:   softwareVersion(),
+		}
+	}
+
+	processed, err := s.processImage(path, buf, s.format(urlConfig), s.quality(viewport), meta)
+	if err != nil {
+		return "", err
+	}
+	path = processed.path
+	data := processed.data
+
+	if !s.Manifest.reserveImage(s.Config.MaxImages) {
+		return "", fmt.Errorf("%w: %s would exceed maxImages %d", errImageBudgetExceeded, filepath.Base(path), s.Config.MaxImages)
+	}
+
+	if err := s.Sink.Write(path, data); err != nil {
+		return "", err
+	}
+
+	// A failed thumbnail is logged; the full image is already written
+	thumbnail := ""
+	if s.Config.ThumbnailWidth > 0 {
+		if thumbnail, err = s.writeThumbnail(path, processed.data, s.quality(viewport)); err != nil {
+			log.Printf("Warning: failed to write thumbnail for %s: %v", filepath.Base(path), err)
+		}
+	}
+
+	s.Manifest.addFile(ManifestFile{
+		Name:      urlConfig.Name,
+		Profile:   urlConfig.ActiveProfile,
+		Viewport:  viewportName,
+		Type:      kind,
+		Path:      path,
+		Bytes:     len(data),
+		Quality:   processed.quality,
+		Suspect:   processed.suspect,
+		Thumbnail: thumbnail,
+	})
+	if s.postCapture != nil {
+		s.postCapture.run(s.Manifest, path)
+	}
+
+	return path, nil
+}
+
+// Sampling and tolerance used when measuring how much of an image differs from the background
+const (
+	contentSampleStep = 4
+	contentTolerance  = 8 // Per-channel difference (0-255) still treated as background
+)
+
+// contentRatio returns the fraction of sampled pixels that differ from the background color
+func contentRatio(img image.Image, background color.RGBA) float64 {
+	bounds := img.Bounds()
+	sampled, content := 0, 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += contentSampleStep {
+		for x := bounds.Min.X; x < bounds.Max.X; x += contentSampleStep {
+			r, g, b, _ := img.At(x, y).RGBA()
+			sampled++
+			if channelDiff(r>>8, background.R) > contentTolerance ||
+				channelDiff(g>>8, background.G) > contentTolerance ||
+				channelDiff(b>>8, background.B) > contentTolerance {
+				content++
+			}
+		}
+	}
+	if sampled == 0 {
+		return 0
+	}
+	return float64(content) / float64(sampled)
+}
+
+// channelDiff returns the absolute difference between two 8-bit color channels
+func channelDiff(a uint32, b uint8) uint32 {
+	if a > uint32(b) {
+		return a - uint32(b)
+	}
+	return uint32(b) - a
+}
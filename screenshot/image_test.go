@@ -0,0 +1,80 @@
+package screenshot
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"screenshot-tool/config"
+)
+
+func TestEncodeImage(t *testing.T) {
+	img := testImage(33, 21)
+	buf, err := encodePNG(img, png.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for level := range pngCompressionLevels {
+		s := &Screenshoter{Config: &config.Config{PNGCompression: level}}
+		out, err := s.encodeImage(img, buf, "png", 0)
+		if err != nil {
+			t.Fatalf("png %s: %v", level, err)
+		}
+		decoded, err := png.Decode(bytes.NewReader(out))
+		if err != nil {
+			t.Fatalf("png %s: output does not decode: %v", level, err)
+		}
+		if decoded.Bounds() != img.Bounds() {
+			t.Errorf("png %s: decoded bounds %v, want %v", level, decoded.Bounds(), img.Bounds())
+		}
+		if !sameImages(img, decoded) {
+			t.Errorf("png %s: recompressed pixels differ", level)
+		}
+	}
+
+	// Without PNGCompression the captured buffer is kept as is
+	s := &Screenshoter{Config: &config.Config{}}
+	out, err := s.encodeImage(img, buf, "png", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, buf) {
+		t.Errorf("png without compression setting was re-encoded")
+	}
+
+	for _, progressive := range []bool{false, true} {
+		s := &Screenshoter{Config: &config.Config{ProgressiveJPEG: progressive}}
+		out, err := s.encodeImage(img, buf, "jpeg", 80)
+		if err != nil {
+			t.Fatalf("jpeg progressive=%v: %v", progressive, err)
+		}
+		decoded, err := jpeg.Decode(bytes.NewReader(out))
+		if err != nil {
+			t.Fatalf("jpeg progressive=%v: output does not decode: %v", progressive, err)
+		}
+		if decoded.Bounds().Size() != img.Bounds().Size() {
+			t.Errorf("jpeg progressive=%v: decoded size %v, want %v", progressive, decoded.Bounds().Size(), img.Bounds().Size())
+		}
+	}
+}
+
+// sameImages reports whether two images have the same bounds and pixels
+func sameImages(a, b image.Image) bool {
+	bounds := a.Bounds()
+	if bounds != b.Bounds() {
+		return false
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, a1 := a.At(x, y).RGBA()
+			r2, g2, b2, a2 := b.At(x, y).RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/chromedp"
+)
+
+// performLogin fills in and submits the URL's login form in the current tab so that the
+// session cookies it sets are present when the target URL is captured
+func (s *Screenshoter) performLogin(ctx context.Context, urlConfig config.URLConfig) error {
+	login := urlConfig.Login
+	log.Printf("Logging in for %s via %s as %s", urlConfig.Name, login.LoginURL, login.Username)
+
+	timeout := time.Duration(login.TimeoutMs) * time.Millisecond
+	loginCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := chromedp.Run(loginCtx,
+		chromedp.Navigate(login.LoginURL),
+		chromedp.WaitVisible(login.UserSelector, chromedp.ByQuery),
+		chromedp.SendKeys(login.UserSelector, login.Username, chromedp.ByQuery),
+		chromedp.SendKeys(login.PassSelector, login.Password, chromedp.ByQuery),
+		chromedp.Click(login.SubmitSelector, chromedp.ByQuery),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to submit login form at %s: %w", login.LoginURL, err)
+	}
+
+	if err := chromedp.Run(loginCtx, chromedp.WaitVisible(login.SuccessSelector, chromedp.ByQuery)); err != nil {
+		return fmt.Errorf("login success selector %q did not appear within %v: %w", login.SuccessSelector, timeout, err)
+	}
+
+	log.Printf("Logged in for %s", urlConfig.Name)
+	return nil
+}
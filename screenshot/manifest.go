@@ -0,0 +1,280 @@
+package screenshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"screenshot-tool/config"
+)
+
+// URL statuses recorded in the manifest
+const (
+	StatusCaptured = "captured"
+	StatusFailed   = "failed"
+	StatusSkipped  = "skipped"
+)
+
+// ManifestEntry records the outcome of capturing a single URL
+type ManifestEntry struct {
+	Name   string   `json:"name"`
+	URL    string   `json:"url"`
+	Tags   []string `json:"tags,omitempty"`
+	Dir    string   `json:"dir,omitempty"`
+	Dirs   []string `json:"dirs,omitempty"` // One directory per viewport in the viewport-first layout, where Dir is unset
+	Status string   `json:"status"`
+	Reason string   `json:"reason,omitempty"` // Why the URL was skipped
+	Error  string   `json:"error,omitempty"`
+
+	Redirects []RedirectHop `json:"redirects,omitempty"` // HTTP redirect chain, ending at the final URL and status
+}
+
+// ManifestFile records a single image written during a run
+type ManifestFile struct {
+	Name             string      `json:"name"`              // URL name the image belongs to
+	Profile          string      `json:"profile,omitempty"` // URL profile the image was captured as
+	Viewport         string      `json:"viewport"`
+	Type             string      `json:"type"` // "full", "full-proof", "viewport", ...
+	Path             string      `json:"path"`
+	Bytes            int         `json:"bytes"`
+	Quality          int         `json:"quality,omitempty"`          // JPEG quality used, lowered when shrinking to fit MaxFileBytes
+	Unchanged        bool        `json:"unchanged,omitempty"`        // Identical to an earlier capture (watch mode or skipUnchanged), Path points at that copy
+	Partial          bool        `json:"partial,omitempty"`          // Best-effort capture after the page failed to finish loading
+	Suspect          bool        `json:"suspect,omitempty"`          // Below Config.MinContentRatio, likely a blank render
+	OffsetMs         int64       `json:"offsetMs,omitempty"`         // Filmstrip frames: time since navigation start
+	Resumed          bool        `json:"resumed,omitempty"`          // Kept from an interrupted run with the same runLabel instead of captured again
+	PostCaptureError string      `json:"postCaptureError,omitempty"` // Why Config.PostCaptureCommand failed on this image
+	Scrolls          int         `json:"scrolls,omitempty"`          // Infinite-scroll steps taken before the capture (URLConfig.MaxScrolls)
+	Element          *ElementBox `json:"element,omitempty"`          // EachSelector captures: which match and where it is on the page
+	Thumbnail        string      `json:"thumbnail,omitempty"`        // Downscaled copy written for Config.ThumbnailWidth
+}
+
+// Manifest records the outcome of a capture run
+type Manifest struct {
+	StartedAt  time.Time       `json:"startedAt"`
+	FinishedAt time.Time       `json:"finishedAt"`
+	URLs       []ManifestEntry `json:"urls"`
+	Files      []ManifestFile  `json:"files"`
+
+	mu        sync.Mutex
+	images    int                      // Images reserved so far, checked against Config.MaxImages
+	redirects map[string][]RedirectHop // Redirect chains by URL name, until the URL's entry is added
+	scrolls   map[string]int           // Scrolls taken by the latest capture in each viewport directory
+}
+
+// addURL records the outcome for a URL
+func (m *Manifest) addURL(entry ManifestEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.URLs = append(m.URLs, entry)
+}
+
+// addFile records an image written during the run
+func (m *Manifest) addFile(file ManifestFile) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if file.Scrolls == 0 {
+		file.Scrolls = m.scrolls[filepath.Dir(file.Path)]
+	}
+	m.Files = append(m.Files, file)
+}
+
+// setRedirects stores the redirect chain for a URL name unless one was already recorded by
+// another viewport, reporting whether it was stored
+func (m *Manifest) setRedirects(name string, hops []RedirectHop) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.redirects[name]; ok {
+		return false
+	}
+	if m.redirects == nil {
+		m.redirects = make(map[string][]RedirectHop)
+	}
+	m.redirects[name] = hops
+	return true
+}
+
+// takeRedirects returns and forgets the redirect chain recorded for a URL name
+func (m *Manifest) takeRedirects(name string) []RedirectHop {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hops := m.redirects[name]
+	delete(m.redirects, name)
+	return hops
+}
+
+// setScrolls records the scrolls taken before the next capture in viewportDir
+func (m *Manifest) setScrolls(viewportDir string, scrolls int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.scrolls == nil {
+		m.scrolls = make(map[string]int)
+	}
+	m.scrolls[viewportDir] = scrolls
+}
+
+// reserveImage counts an image about to be written and reports whether it fits within limit.
+// A limit of 0 disables the check.
+func (m *Manifest) reserveImage(limit int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if limit > 0 && m.images >= limit {
+		return false
+	}
+	m.images++
+	return true
+}
+
+// hasSuspectFiles reports whether any image under dir was flagged as suspect
+func (m *Manifest) hasSuspectFiles(dir string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, file := range m.Files {
+		if file.Suspect && filepath.Dir(file.Path) == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// filesIn returns the images recorded under dir, including its filmstrip
+func (m *Manifest) filesIn(dir string) []ManifestFile {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var files []ManifestFile
+	for _, file := range m.Files {
+		if underDir(file.Path, dir) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// removeFiles deletes the images under dir, including its filmstrip, with remove and drops
+// them from the manifest
+func (m *Manifest) removeFiles(dir string, remove func(path string) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := m.Files[:0]
+	for _, file := range m.Files {
+		if !underDir(file.Path, dir) {
+			kept = append(kept, file)
+			continue
+		}
+		if err := remove(file.Path); err != nil {
+			log.Printf("Warning: failed to remove %s: %v", file.Path, err)
+		}
+	}
+	m.Files = kept
+}
+
+// updateFile applies fn to the recorded file with the given path
+func (m *Manifest) updateFile(path string, fn func(file *ManifestFile)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.Files {
+		if m.Files[i].Path == path {
+			fn(&m.Files[i])
+			return
+		}
+	}
+}
+
+// recordSkipped records a URL that was filtered out of the run
+func (m *Manifest) recordSkipped(urlConfig config.URLConfig, reason string) {
+	m.addURL(ManifestEntry{
+		Name:   urlConfig.Name,
+		URL:    urlConfig.URL,
+		Tags:   urlConfig.Tags,
+		Status: StatusSkipped,
+		Reason: reason,
+	})
+}
+
+// writeManifest writes the manifest as JSON into the output directory
+func (m *Manifest) writeManifest(outputDir string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	path := filepath.Join(outputDir, "manifest.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return path, nil
+}
+
+// RunSummary is the end-of-run tally printed by CaptureURLs for quick CI checks
+type RunSummary struct {
+	URLs      int   `json:"urls"`
+	Succeeded int   `json:"succeeded"`
+	Failed    int   `json:"failed"`
+	Skipped   int   `json:"skipped"`
+	Images    int   `json:"images"` // Including images reused from earlier runs
+	Bytes     int64 `json:"bytes"`
+	ElapsedMs int64 `json:"elapsedMs"`
+}
+
+// summary tallies the URLs and images recorded so far
+func (m *Manifest) summary() RunSummary {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elapsed := m.FinishedAt.Sub(m.StartedAt)
+	summary := RunSummary{
+		URLs:      len(m.URLs),
+		Images:    len(m.Files),
+		ElapsedMs: elapsed.Milliseconds(),
+	}
+	for _, entry := range m.URLs {
+		switch entry.Status {
+		case StatusCaptured:
+			summary.Succeeded++
+		case StatusFailed:
+			summary.Failed++
+		case StatusSkipped:
+			summary.Skipped++
+		}
+	}
+	for _, file := range m.Files {
+		summary.Bytes += int64(file.Bytes)
+	}
+	return summary
+}
+
+// String formats the summary as a single human-readable line
+func (r RunSummary) String() string {
+	return fmt.Sprintf("Run summary: %d URLs, %d succeeded, %d failed, %d skipped, %d images, %d bytes in %v",
+		r.URLs, r.Succeeded, r.Failed, r.Skipped, r.Images, r.Bytes, (time.Duration(r.ElapsedMs) * time.Millisecond).Round(time.Millisecond))
+}
+
+// printSummary logs the run's tally and, with JSONSummary, prints it to stdout as one JSON object
+func (s *Screenshoter) printSummary() {
+	summary := s.Manifest.summary()
+	log.Print(summary)
+	if !s.Config.JSONSummary {
+		return
+	}
+	data, err := json.Marshal(summary)
+	if err != nil {
+		log.Printf("ERROR: failed to encode run summary: %v", err)
+		return
+	}
+	fmt.Println(string(data))
+}
+
+// underDir reports whether path is in dir or its filmstrip subdirectory
+func underDir(path, dir string) bool {
+	parent := filepath.Dir(path)
+	return parent == dir || parent == filepath.Join(dir, filmstripDir)
+}
@@ -0,0 +1,316 @@
+package screenshot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"runtime/debug"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ImageMetadata is the provenance embedded into images when Config.EmbedMetadata is set
+type ImageMetadata struct {
+	Name       string    // URL name from the config
+	URL        string    // Captured URL
+	Viewport   string    // e.g. "1280x800"
+	Type       string    // "full", "viewport", ...
+	CapturedAt time.Time // When the image was written
+	Software   string    // Tool and build information
+}
+
+// PNG text keywords used for each metadata field
+const (
+	pngKeyName       = "Title"
+	pngKeyURL        = "Source"
+	pngKeyViewport   = "Viewport"
+	pngKeyType       = "Capture Type"
+	pngKeyCapturedAt = "Creation Time"
+	pngKeySoftware   = "Software"
+)
+
+// XMP namespaces and the APP1 header identifying an XMP packet in a JPEG
+const (
+	xmpHeader     = "http://ns.adobe.com/xap/1.0/\x00"
+	xmpNamespace  = "http://ns.adobe.com/xap/1.0/"
+	dcNamespace   = "http://purl.org/dc/elements/1.1/"
+	toolNamespace = "https://github.com/aroksetx/go-page-screenshot-report/ns/1.0/"
+	rdfNamespace  = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+)
+
+const (
+	pngSignature   = "\x89PNG\r\n\x1a\n"
+	maxJPEGSegment = 0xffff // Largest JPEG segment, including its length field
+)
+
+// softwareVersion describes this build for the Software metadata field
+var softwareVersion = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "screenshot-tool"
+	}
+	version := info.Main.Version
+	if version == "" {
+		version = "(devel)"
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" && len(setting.Value) >= 12 {
+			version += " " + setting.Value[:12]
+		}
+	}
+	return fmt.Sprintf("screenshot-tool %s (%s)", version, info.GoVersion)
+})
+
+// embedMetadata returns a copy of a PNG or JPEG image with meta embedded: PNG text chunks
+// after the header, or an XMP packet at the start of a JPEG
+func embedMetadata(data []byte, meta ImageMetadata) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte(pngSignature)):
+		return embedPNGText(data, meta)
+	case bytes.HasPrefix(data, []byte{0xff, 0xd8}):
+		return embedJPEGXMP(data, meta)
+	default:
+		return nil, errors.New("cannot embed metadata: not a PNG or JPEG image")
+	}
+}
+
+// embedPNGText inserts the text chunks of meta right after the IHDR chunk
+func embedPNGText(data []byte, meta ImageMetadata) ([]byte, error) {
+	headerEnd := len(pngSignature) + 8 + 13 + 4 // Signature, IHDR length and type, data, CRC
+	if len(data) < headerEnd || string(data[len(pngSignature)+4:len(pngSignature)+8]) != "IHDR" {
+		return nil, errors.New("cannot embed metadata: PNG does not start with IHDR")
+	}
+
+	var out bytes.Buffer
+	out.Write(data[:headerEnd])
+	out.Write(pngTextChunks(meta))
+	out.Write(data[headerEnd:])
+	return out.Bytes(), nil
+}
+
+// pngTextChunks returns one text chunk per field of meta. ASCII values use tEXt; anything else
+// uses uncompressed iTXt, which holds UTF-8.
+func pngTextChunks(meta ImageMetadata) []byte {
+	var out bytes.Buffer
+	for _, field := range [][2]string{
+		{pngKeyName, meta.Name},
+		{pngKeyURL, meta.URL},
+		{pngKeyViewport, meta.Viewport},
+		{pngKeyType, meta.Type},
+		{pngKeyCapturedAt, meta.CapturedAt.Format(time.RFC3339)},
+		{pngKeySoftware, meta.Software},
+	} {
+		keyword, text := field[0], field[1]
+		if isASCII(text) {
+			writePNGChunk(&out, "tEXt", []byte(keyword+"\x00"+text))
+		} else {
+			// Keyword, compression flag and method, then empty language tag and translated keyword
+			writePNGChunk(&out, "iTXt", []byte(keyword+"\x00\x00\x00\x00\x00"+text))
+		}
+	}
+	return out.Bytes()
+}
+
+// writePNGChunk writes a PNG chunk with its length and CRC
+func writePNGChunk(out *bytes.Buffer, chunkType string, payload []byte) {
+	binary.Write(out, binary.BigEndian, uint32(len(payload)))
+	out.WriteString(chunkType)
+	out.Write(payload)
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(chunkType))
+	crc.Write(payload)
+	binary.Write(out, binary.BigEndian, crc.Sum32())
+}
+
+// isASCII reports whether text can be stored in a PNG tEXt chunk as is. tEXt holds Latin-1, whose
+// bytes 0x80-0xff mean something different than in UTF-8, so only ASCII goes there.
+func isASCII(text string) bool {
+	for i := 0; i < len(text); i++ {
+		if text[i] >= utf8.RuneSelf || text[i] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// embedJPEGXMP inserts an XMP packet in an APP1 segment after SOI and any JFIF APP0 segment
+func embedJPEGXMP(data []byte, meta ImageMetadata) ([]byte, error) {
+	packet := xmpPacket(meta)
+	length := 2 + len(xmpHeader) + len(packet)
+	if length > maxJPEGSegment {
+		return nil, fmt.Errorf("cannot embed metadata: XMP packet of %d bytes does not fit in a JPEG segment", length)
+	}
+
+	insertAt := 2
+	if len(data) >= 6 && data[2] == 0xff && data[3] == 0xe0 {
+		insertAt = 4 + int(binary.BigEndian.Uint16(data[4:6]))
+		if insertAt > len(data) {
+			return nil, errors.New("cannot embed metadata: truncated JPEG APP0 segment")
+		}
+	}
+
+	var out bytes.Buffer
+	out.Write(data[:insertAt])
+	out.Write([]byte{0xff, 0xe1, byte(length >> 8), byte(length)})
+	out.WriteString(xmpHeader)
+	out.WriteString(packet)
+	out.Write(data[insertAt:])
+	return out.Bytes(), nil
+}
+
+// xmpPacket returns the XMP packet describing meta
+func xmpPacket(meta ImageMetadata) string {
+	attr := func(name, value string) string {
+		var escaped bytes.Buffer
+		xml.EscapeText(&escaped, []byte(value))
+		return fmt.Sprintf("\n   %s=\"%s\"", name, escaped.String())
+	}
+	return "<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>" +
+		`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="` + rdfNamespace + `">` +
+		`<rdf:Description rdf:about=""` +
+		` xmlns:xmp="` + xmpNamespace + `" xmlns:dc="` + dcNamespace + `" xmlns:st="` + toolNamespace + `"` +
+		attr("st:Name", meta.Name) +
+		attr("dc:source", meta.URL) +
+		attr("st:Viewport", meta.Viewport) +
+		attr("st:Type", meta.Type) +
+		attr("xmp:CreateDate", meta.CapturedAt.Format(time.RFC3339)) +
+		attr("xmp:CreatorTool", meta.Software) +
+		`/></rdf:RDF></x:xmpmeta><?xpacket end="r"?>`
+}
+
+// metadataSize returns how many bytes embedMetadata adds to an image of the given format
+func metadataSize(format string, meta ImageMetadata) int {
+	if format == "jpeg" {
+		return 4 + len(xmpHeader) + len(xmpPacket(meta)) // Marker and length, header, packet
+	}
+	return len(pngTextChunks(meta))
+}
+
+// ReadMetadata returns the provenance embedded by Config.EmbedMetadata in a PNG or JPEG image.
+// It returns an error when the image carries none.
+func ReadMetadata(data []byte) (ImageMetadata, error) {
+	var fields map[string]string
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte(pngSignature)):
+		fields, err = readPNGText(data)
+	case bytes.HasPrefix(data, []byte{0xff, 0xd8}):
+		fields, err = readJPEGXMP(data)
+	default:
+		return ImageMetadata{}, errors.New("not a PNG or JPEG image")
+	}
+	if err != nil {
+		return ImageMetadata{}, err
+	}
+
+	meta := ImageMetadata{
+		Name:     fields[pngKeyName],
+		URL:      fields[pngKeyURL],
+		Viewport: fields[pngKeyViewport],
+		Type:     fields[pngKeyType],
+		Software: fields[pngKeySoftware],
+	}
+	if value := fields[pngKeyCapturedAt]; value != "" {
+		if meta.CapturedAt, err = time.Parse(time.RFC3339, value); err != nil {
+			return ImageMetadata{}, fmt.Errorf("invalid capture time %q: %w", value, err)
+		}
+	}
+	if meta == (ImageMetadata{}) {
+		return ImageMetadata{}, errors.New("image has no embedded capture metadata")
+	}
+	return meta, nil
+}
+
+// readPNGText collects the tEXt and iTXt chunks of a PNG by keyword
+func readPNGText(data []byte) (map[string]string, error) {
+	fields := make(map[string]string)
+	for pos := len(pngSignature); pos+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		chunkType := string(data[pos+4 : pos+8])
+		if length < 0 || pos+12+length > len(data) {
+			return nil, errors.New("truncated PNG chunk")
+		}
+		payload := data[pos+8 : pos+8+length]
+
+		switch chunkType {
+		case "tEXt":
+			if keyword, text, ok := bytes.Cut(payload, []byte{0}); ok {
+				fields[string(keyword)] = string(text)
+			}
+		case "iTXt":
+			// Keyword, compression flag and method, language tag, translated keyword, text
+			keyword, rest, ok := bytes.Cut(payload, []byte{0})
+			if ok && len(rest) >= 2 && rest[0] == 0 {
+				parts := bytes.SplitN(rest[2:], []byte{0}, 3)
+				if len(parts) == 3 {
+					fields[string(keyword)] = string(parts[2])
+				}
+			}
+		case "IDAT", "IEND":
+			return fields, nil
+		}
+		pos += 12 + length
+	}
+	return fields, nil
+}
+
+// readJPEGXMP finds the XMP packet among the segments before the image data and returns its
+// properties under the matching PNG keywords
+func readJPEGXMP(data []byte) (map[string]string, error) {
+	for pos := 2; pos+4 <= len(data) && data[pos] == 0xff; {
+		marker := data[pos+1]
+		if marker == 0xda || marker == 0xd9 { // SOS, EOI
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return nil, errors.New("truncated JPEG segment")
+		}
+		payload := data[pos+4 : pos+2+length]
+		if marker == 0xe1 && bytes.HasPrefix(payload, []byte(xmpHeader)) {
+			return parseXMP(payload[len(xmpHeader):])
+		}
+		pos += 2 + length
+	}
+	return nil, errors.New("image has no embedded capture metadata")
+}
+
+// parseXMP reads the properties written by embedJPEGXMP from the rdf:Description attributes
+func parseXMP(packet []byte) (map[string]string, error) {
+	keys := map[xml.Name]string{
+		{Space: toolNamespace, Local: "Name"}:       pngKeyName,
+		{Space: dcNamespace, Local: "source"}:       pngKeyURL,
+		{Space: toolNamespace, Local: "Viewport"}:   pngKeyViewport,
+		{Space: toolNamespace, Local: "Type"}:       pngKeyType,
+		{Space: xmpNamespace, Local: "CreateDate"}:  pngKeyCapturedAt,
+		{Space: xmpNamespace, Local: "CreatorTool"}: pngKeySoftware,
+	}
+
+	fields := make(map[string]string)
+	decoder := xml.NewDecoder(bytes.NewReader(packet))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if len(fields) > 0 {
+				return fields, nil
+			}
+			return nil, fmt.Errorf("invalid XMP packet: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Space != rdfNamespace || start.Name.Local != "Description" {
+			continue
+		}
+		for _, a := range start.Attr {
+			if key, ok := keys[a.Name]; ok {
+				fields[key] = a.Value
+			}
+		}
+		if len(fields) > 0 {
+			return fields, nil
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package screenshot
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"screenshot-tool/config"
+)
+
+func testMetadata() ImageMetadata {
+	return ImageMetadata{
+		Name:       `Café & "Bar" <Menu>`,
+		URL:        "https://example.com/search?q=a&b=<c>&lang=日本語",
+		Viewport:   "1280x800",
+		Type:       "full",
+		CapturedAt: time.Date(2025, 3, 14, 15, 9, 26, 0, time.UTC),
+		Software:   "screenshot-tool (devel) 'test'",
+	}
+}
+
+func TestMetadataRoundTrip(t *testing.T) {
+	img := testImage(40, 30)
+	pngData, err := encodePNG(img, png.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baselineData, err := encodeJPEG(img, 80, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	progressiveData, err := encodeJPEG(img, 80, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, meta := range []ImageMetadata{
+		testMetadata(),
+		{Name: "ascii", URL: "https://example.com/", Viewport: "375x667", Type: "viewport", CapturedAt: time.Unix(0, 0).UTC()},
+	} {
+		for name, data := range map[string][]byte{"png": pngData, "jpeg": baselineData, "progressive": progressiveData} {
+			embedded, err := embedMetadata(data, meta)
+			if err != nil {
+				t.Fatalf("%s: embed failed: %v", name, err)
+			}
+			format := "png"
+			if name != "png" {
+				format = "jpeg"
+			}
+			if got, want := len(embedded)-len(data), metadataSize(format, meta); got != want {
+				t.Errorf("%s: embedding added %d bytes, metadataSize says %d", name, got, want)
+			}
+
+			got, err := ReadMetadata(embedded)
+			if err != nil {
+				t.Fatalf("%s: read failed: %v", name, err)
+			}
+			if got != meta {
+				t.Errorf("%s: read %+v, want %+v", name, got, meta)
+			}
+
+			decoded, _, err := image.Decode(bytes.NewReader(embedded))
+			if err != nil {
+				t.Fatalf("%s: image with metadata does not decode: %v", name, err)
+			}
+			if decoded.Bounds() != img.Bounds() {
+				t.Errorf("%s: decoded bounds %v, want %v", name, decoded.Bounds(), img.Bounds())
+			}
+		}
+	}
+}
+
+func TestReadMetadataWithoutMetadata(t *testing.T) {
+	img := testImage(8, 8)
+	pngData, err := encodePNG(img, png.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jpegData, err := encodeJPEG(img, 80, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{"png": pngData, "jpeg": jpegData, "other": []byte("GIF89a")} {
+		if _, err := ReadMetadata(data); err == nil {
+			t.Errorf("%s: expected an error for an image without metadata", name)
+		}
+	}
+}
+
+func TestProcessImageMetadataWithinMaxFileBytes(t *testing.T) {
+	// Noise compresses badly, so the image has to be shrunk to fit
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(0, 0, 200, 150))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(rng.Intn(256))
+		if i%4 == 3 {
+			img.Pix[i] = 255
+		}
+	}
+	buf, err := encodePNG(img, png.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta := testMetadata()
+	meta.Software = strings.Repeat("x", 2000)
+
+	for _, format := range []string{"png", "jpeg"} {
+		s := &Screenshoter{Config: &config.Config{
+			FileFormat:          format,
+			Quality:             90,
+			MaxFileBytes:        20000,
+			ConvertOversizedPNG: true,
+		}}
+		processed, err := s.processImage("test."+format, buf, format, 90, &meta)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if len(processed.data) > s.Config.MaxFileBytes {
+			t.Errorf("%s: %d bytes with metadata, over the %d byte limit", format, len(processed.data), s.Config.MaxFileBytes)
+		}
+		if got, err := ReadMetadata(processed.data); err != nil || got != meta {
+			t.Errorf("%s: read %+v, %v, want %+v", format, got, err, meta)
+		}
+	}
+}
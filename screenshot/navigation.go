@@ -0,0 +1,101 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// navigateTo loads urlstr in the tab, returning at the point Config.NavigateWaitUntil names
+func (s *Screenshoter) navigateTo(urlstr string) chromedp.Action {
+	switch s.Config.NavigateWaitUntil {
+	case config.NavigateWaitDOMContentLoaded:
+		return awaitDOMContentLoaded(chromedp.ActionFunc(func(ctx context.Context) error {
+			return pageNavigate(ctx, urlstr)
+		}))
+	case config.NavigateWaitNone:
+		return chromedp.ActionFunc(func(ctx context.Context) error {
+			return pageNavigate(ctx, urlstr)
+		})
+	default:
+		return chromedp.Navigate(urlstr)
+	}
+}
+
+// reload reloads the page in the tab, returning at the point Config.NavigateWaitUntil names
+func (s *Screenshoter) reload() chromedp.Action {
+	switch s.Config.NavigateWaitUntil {
+	case config.NavigateWaitDOMContentLoaded:
+		return awaitDOMContentLoaded(page.Reload())
+	case config.NavigateWaitNone:
+		return page.Reload()
+	default:
+		return chromedp.Reload()
+	}
+}
+
+// pageNavigate starts loading urlstr and returns once the response starts arriving, which is
+// all page.Navigate waits for
+func pageNavigate(ctx context.Context, urlstr string) error {
+	_, _, errorText, err := page.Navigate(urlstr).Do(ctx)
+	if err != nil {
+		return err
+	}
+	if errorText != "" {
+		return fmt.Errorf("page load error %s", errorText)
+	}
+	return nil
+}
+
+// awaitDOMContentLoaded runs trigger and waits until the document it loads into the main frame
+// fires DOMContentLoaded, without waiting for the load event. Same-document navigations finish
+// right away.
+func awaitDOMContentLoaded(trigger chromedp.Action) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		frameTree, err := page.GetFrameTree().Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to find the main frame: %w", err)
+		}
+		mainFrame := frameTree.Frame.ID
+
+		// The new document is the first one the main frame initializes after the trigger
+		var once sync.Once
+		done := make(chan struct{})
+		var loaderID cdp.LoaderID
+		listenCtx, cancelListen := context.WithCancel(ctx)
+		defer cancelListen()
+		chromedp.ListenTarget(listenCtx, func(ev interface{}) {
+			switch ev := ev.(type) {
+			case *page.EventLifecycleEvent:
+				if ev.FrameID != mainFrame {
+					return
+				}
+				if ev.Name == "init" && loaderID == "" {
+					loaderID = ev.LoaderID
+				} else if ev.Name == "DOMContentLoaded" && ev.LoaderID == loaderID {
+					once.Do(func() { close(done) })
+				}
+			case *page.EventNavigatedWithinDocument:
+				if ev.FrameID == mainFrame {
+					once.Do(func() { close(done) })
+				}
+			}
+		})
+
+		if err := trigger.Do(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
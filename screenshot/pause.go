@@ -0,0 +1,77 @@
+package screenshot
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// pauseGate holds back new captures while a run is paused
+type pauseGate struct {
+	mu      sync.Mutex
+	resumed chan struct{} // Closed when not paused; replaced by an open channel on pause
+}
+
+func newPauseGate() *pauseGate {
+	resumed := make(chan struct{})
+	close(resumed)
+	return &pauseGate{resumed: resumed}
+}
+
+// pause makes wait block until resume, reporting whether the gate was open
+func (g *pauseGate) pause() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	select {
+	case <-g.resumed:
+		g.resumed = make(chan struct{})
+		return true
+	default:
+		return false
+	}
+}
+
+// resume releases everything blocked in wait, reporting whether the gate was paused
+func (g *pauseGate) resume() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	select {
+	case <-g.resumed:
+		return false
+	default:
+		close(g.resumed)
+		return true
+	}
+}
+
+// wait blocks while the gate is paused, returning early if ctx is cancelled. A nil gate is
+// never paused.
+func (g *pauseGate) wait(ctx context.Context) error {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	resumed := g.resumed
+	g.mu.Unlock()
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Pause stops CaptureURLs from starting further URLs until Resume is called. Captures already
+// in progress finish normally. Pausing an already paused Screenshoter does nothing.
+func (s *Screenshoter) Pause() {
+	if s.pauseGate.pause() {
+		log.Printf("Paused: captures in progress will finish, new ones wait for resume")
+	}
+}
+
+// Resume lets CaptureURLs start URLs again after Pause
+func (s *Screenshoter) Resume() {
+	if s.pauseGate.resume() {
+		log.Printf("Resumed: starting new captures again")
+	}
+}
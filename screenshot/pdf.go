@@ -0,0 +1,65 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/cdproto/page"
+)
+
+// Margins of the printed PDF in inches, leaving room for the header and footer
+const (
+	pdfMarginVertical   = 0.6
+	pdfMarginHorizontal = 0.4
+)
+
+// savePDF prints the page to <name>.pdf with Config.PDFHeaderTemplate and
+// Config.PDFFooterTemplate on every page
+func (s *Screenshoter) savePDF(ctx context.Context, urlConfig config.URLConfig, viewportDir string) error {
+	now := time.Now()
+	data, _, err := page.PrintToPDF().
+		WithPrintBackground(true).
+		WithDisplayHeaderFooter(true).
+		WithHeaderTemplate(renderPDFTemplate(s.Config.PDFHeaderTemplate, urlConfig.URL, now)).
+		WithFooterTemplate(renderPDFTemplate(s.Config.PDFFooterTemplate, urlConfig.URL, now)).
+		WithMarginTop(pdfMarginVertical).
+		WithMarginBottom(pdfMarginVertical).
+		WithMarginLeft(pdfMarginHorizontal).
+		WithMarginRight(pdfMarginHorizontal).
+		Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to print PDF: %w", err)
+	}
+
+	path := filepath.Join(viewportDir, sanitizeFilename(urlConfig.Name)+".pdf")
+	if err := s.Sink.Write(path, data); err != nil {
+		return fmt.Errorf("failed to write PDF: %w", err)
+	}
+
+	log.Printf("Saved PDF for %s: %s", urlConfig.Name, path)
+	return nil
+}
+
+// renderPDFTemplate fills in the placeholders of a PDF header or footer template. The source URL
+// and capture date are filled in as escaped text; the title and page numbers become the elements
+// Chrome fills in per page. An empty template renders as an empty element, since Chrome prints
+// its own header or footer for an empty one.
+func renderPDFTemplate(template, pageURL string, capturedAt time.Time) string {
+	if template == "" {
+		return "<span></span>"
+	}
+	return strings.NewReplacer(
+		"{url}", html.EscapeString(pageURL),
+		"{date}", html.EscapeString(capturedAt.Format("2006-01-02 15:04:05 MST")),
+		"{title}", `<span class="title"></span>`,
+		"{pageNumber}", `<span class="pageNumber"></span>`,
+		"{totalPages}", `<span class="totalPages"></span>`,
+	).Replace(template)
+}
@@ -0,0 +1,87 @@
+package screenshot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"screenshot-tool/config"
+)
+
+// postCaptureTimeout bounds a single run of Config.PostCaptureCommand
+const postCaptureTimeout = 5 * time.Minute
+
+// postCaptureRunner runs Config.PostCaptureCommand on each image as it is written, at most
+// Config.PostCaptureConcurrency at a time, in the background of the captures
+type postCaptureRunner struct {
+	ctx  context.Context
+	args []string // Command template, split into arguments
+	sem  chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newPostCaptureRunner prepares the configured command, or returns nil when there is none
+func newPostCaptureRunner(ctx context.Context, cfg *config.Config) (*postCaptureRunner, error) {
+	if cfg.PostCaptureCommand == "" {
+		return nil, nil
+	}
+	args, err := config.SplitCommand(cfg.PostCaptureCommand)
+	if err != nil {
+		return nil, fmt.Errorf("invalid postCaptureCommand: %w", err)
+	}
+	return &postCaptureRunner{
+		ctx:  ctx,
+		args: args,
+		sem:  make(chan struct{}, cfg.PostCaptureConcurrency),
+	}, nil
+}
+
+// run starts the command for the image at path. A failure is logged and recorded on the
+// image's manifest entry; it does not fail the capture.
+func (r *postCaptureRunner) run(manifest *Manifest, path string) {
+	args := make([]string, len(r.args))
+	for i, arg := range r.args {
+		args[i] = strings.ReplaceAll(arg, "{path}", path)
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.sem <- struct{}{}
+		defer func() { <-r.sem }()
+
+		ctx, cancel := context.WithTimeout(r.ctx, postCaptureTimeout)
+		defer cancel()
+
+		start := time.Now()
+		output, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+		if output = bytes.TrimSpace(output); len(output) > 0 {
+			log.Printf("postCaptureCommand output for %s:\n%s", path, output)
+		}
+		if err != nil {
+			log.Printf("ERROR: postCaptureCommand failed for %s: %v", path, err)
+			manifest.updateFile(path, func(file *ManifestFile) { file.PostCaptureError = err.Error() })
+			return
+		}
+		log.Printf("postCaptureCommand finished for %s in %v", path, time.Since(start))
+
+		// The command may have rewritten the image, e.g. to optimize it
+		if info, err := os.Stat(path); err == nil {
+			manifest.updateFile(path, func(file *ManifestFile) { file.Bytes = int(info.Size()) })
+		}
+	}()
+}
+
+// wait blocks until every started command has finished
+func (r *postCaptureRunner) wait() {
+	if r == nil {
+		return
+	}
+	r.wg.Wait()
+}
@@ -0,0 +1,66 @@
+package screenshot
+
+import (
+	"fmt"
+	"log"
+
+	"screenshot-tool/config"
+)
+
+// profileVariant is one way of capturing a URL: as configured, or as one of its profiles
+type profileVariant struct {
+	urlConfig config.URLConfig
+	dirName   string // Subdirectory of the URL directory, empty when there are no profiles
+}
+
+// profileVariants returns the URL once per profile, each with the profile's cookies and
+// localStorage on top of the URL's own (the profile wins by cookie name or storage key), or
+// the URL unchanged when it has no profiles
+func (s *Screenshoter) profileVariants(urlConfig config.URLConfig) []profileVariant {
+	if len(urlConfig.Profiles) == 0 {
+		return []profileVariant{{urlConfig: urlConfig}}
+	}
+
+	variants := make([]profileVariant, 0, len(urlConfig.Profiles))
+	usedDirs := make(map[string]bool)
+	for _, profile := range urlConfig.Profiles {
+		// Distinct names can sanitize to the same directory, e.g. "free user" and "free_user"
+		dirName := sanitizeFilename(profile.Name)
+		for i := 2; usedDirs[dirName]; i++ {
+			dirName = fmt.Sprintf("%s-%d", sanitizeFilename(profile.Name), i)
+		}
+		usedDirs[dirName] = true
+
+		variant := urlConfig
+		variant.ActiveProfile = profile.Name
+
+		variant.Cookies = nil
+		overridden := make(map[string]bool)
+		for _, cookie := range profile.Cookies {
+			overridden[cookie.Name] = true
+		}
+		for _, cookie := range urlConfig.Cookies {
+			if !overridden[cookie.Name] {
+				variant.Cookies = append(variant.Cookies, cookie)
+			}
+		}
+		variant.Cookies = append(variant.Cookies, profile.Cookies...)
+
+		variant.LocalStorage = nil
+		overridden = make(map[string]bool)
+		for _, item := range profile.LocalStorage {
+			overridden[item.Key] = true
+		}
+		for _, item := range urlConfig.LocalStorage {
+			if !overridden[item.Key] {
+				variant.LocalStorage = append(variant.LocalStorage, item)
+			}
+		}
+		variant.LocalStorage = append(variant.LocalStorage, profile.LocalStorage...)
+
+		log.Printf("Profile %q for %s: %d cookies, %d localStorage items",
+			profile.Name, urlConfig.Name, len(variant.Cookies), len(variant.LocalStorage))
+		variants = append(variants, profileVariant{urlConfig: variant, dirName: dirName})
+	}
+	return variants
+}
@@ -0,0 +1,548 @@
+package screenshot
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// Go's image/jpeg only writes baseline JPEGs, so progressive output is encoded here. The image
+// is stored as 4:2:0 YCbCr with the standard quantization tables (the same data image/jpeg
+// would write) in a progressive (SOF2) frame using spectral selection only: one interleaved DC
+// scan with the standard DC Huffman tables, then per-component AC scans of the low and high
+// frequencies. Each AC scan gets its own optimal Huffman table, as in libjpeg, so runs of empty
+// blocks can be coded as end-of-band runs.
+
+// zigzag maps the zig-zag position of a coefficient to its natural (row-major) index
+var zigzag = [64]int{
+	0, 1, 8, 16, 9, 2, 3, 10,
+	17, 24, 32, 25, 18, 11, 4, 5,
+	12, 19, 26, 33, 40, 48, 41, 34,
+	27, 20, 13, 6, 7, 14, 21, 28,
+	35, 42, 49, 56, 57, 50, 43, 36,
+	29, 22, 15, 23, 30, 37, 44, 51,
+	58, 59, 52, 45, 38, 31, 39, 46,
+	53, 60, 61, 54, 47, 55, 62, 63,
+}
+
+// jpegQuant holds the luminance and chrominance quantization tables of JPEG Annex K in
+// zig-zag order, before quality scaling
+var jpegQuant = [2][64]byte{
+	{
+		16, 11, 12, 14, 12, 10, 16, 14,
+		13, 14, 18, 17, 16, 19, 24, 40,
+		26, 24, 22, 22, 24, 49, 35, 37,
+		29, 40, 58, 51, 61, 60, 57, 51,
+		56, 55, 64, 72, 92, 78, 64, 68,
+		87, 69, 55, 56, 80, 109, 81, 87,
+		95, 98, 103, 104, 103, 62, 77, 113,
+		121, 112, 100, 120, 92, 101, 103, 99,
+	},
+	{
+		17, 18, 18, 24, 21, 24, 47, 26,
+		26, 47, 99, 66, 56, 66, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+	},
+}
+
+// huffmanSpec is a Huffman table as stored in a DHT segment: the number of codes of each
+// length from 1 to 16 bits, then the symbols in code order
+type huffmanSpec struct {
+	counts  [16]byte
+	symbols []byte
+}
+
+// jpegDCHuffman holds the luminance and chrominance DC tables of JPEG Annex K
+var jpegDCHuffman = [2]huffmanSpec{
+	{
+		[16]byte{0, 1, 5, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0},
+		[]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+	},
+	{
+		[16]byte{0, 3, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0},
+		[]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+	},
+}
+
+// maxEOBRun is the longest end-of-band run a single symbol can code
+const maxEOBRun = 0x7fff
+
+// progressiveScan is one scan of the progression: components (0 Y, 1 Cb, 2 Cr) and the
+// zig-zag band of coefficients it carries
+type progressiveScan struct {
+	components []int
+	ss, se     int
+}
+
+// progressiveScans sends the DC image first, then enough luminance detail for a recognisable
+// preview, then color, then the remaining luminance detail
+var progressiveScans = []progressiveScan{
+	{components: []int{0, 1, 2}, ss: 0, se: 0},
+	{components: []int{0}, ss: 1, se: 5},
+	{components: []int{1}, ss: 1, se: 63},
+	{components: []int{2}, ss: 1, se: 63},
+	{components: []int{0}, ss: 6, se: 63},
+}
+
+// huffmanCode is the code and bit length of one symbol
+type huffmanCode struct {
+	code   uint32
+	length uint32
+}
+
+// huffmanCodes assigns the canonical codes of a Huffman table to its symbols
+func huffmanCodes(spec huffmanSpec) [256]huffmanCode {
+	var codes [256]huffmanCode
+	code, k := uint32(0), 0
+	for length := 1; length <= 16; length++ {
+		for i := 0; i < int(spec.counts[length-1]); i++ {
+			codes[spec.symbols[k]] = huffmanCode{code: code, length: uint32(length)}
+			code++
+			k++
+		}
+		code <<= 1
+	}
+	return codes
+}
+
+// jpegBitWriter writes entropy-coded data, stuffing a zero byte after every 0xFF
+type jpegBitWriter struct {
+	out   *bytes.Buffer
+	bits  uint32
+	nBits uint32
+}
+
+// emit writes the low n bits of bits, most significant first
+func (w *jpegBitWriter) emit(bits, n uint32) {
+	n += w.nBits
+	bits <<= 32 - n
+	bits |= w.bits
+	for n >= 8 {
+		b := byte(bits >> 24)
+		w.out.WriteByte(b)
+		if b == 0xff {
+			w.out.WriteByte(0x00)
+		}
+		bits <<= 8
+		n -= 8
+	}
+	w.bits, w.nBits = bits, n
+}
+
+// magnitude returns the size category of value and its extra bits
+func magnitude(value int32) (uint32, uint32) {
+	abs, bits := value, uint32(value)
+	if value < 0 {
+		abs = -value
+		bits = uint32(value - 1)
+	}
+	size := uint32(0)
+	for ; abs > 0; abs >>= 1 {
+		size++
+	}
+	return size, bits & (1<<size - 1)
+}
+
+// emitSymbol writes the Huffman code of symbol followed by n extra bits
+func (w *jpegBitWriter) emitSymbol(codes *[256]huffmanCode, symbol uint8, extra, n uint32) {
+	code := codes[symbol]
+	w.emit(code.code, code.length)
+	if n > 0 {
+		w.emit(extra, n)
+	}
+}
+
+// flush pads the last byte with one bits
+func (w *jpegBitWriter) flush() {
+	w.emit(0x7f, 7)
+	w.bits, w.nBits = 0, 0
+}
+
+// jpegComponent holds the quantized coefficients of one component in zig-zag order, for every
+// block of the MCU-aligned grid
+type jpegComponent struct {
+	blocksX, blocksY int // Size of the MCU-aligned block grid
+	usedX, usedY     int // Blocks covering the component's own area, coded by non-interleaved scans
+	sampling         int // Blocks per MCU in each direction
+	table            int // Quantization and Huffman table: 0 luminance, 1 chrominance
+	coefficients     []int16
+}
+
+// block returns the coefficients of the block at (bx, by)
+func (c *jpegComponent) block(bx, by int) []int16 {
+	start := (by*c.blocksX + bx) * 64
+	return c.coefficients[start : start+64]
+}
+
+// encodeProgressiveJPEG encodes img as a progressive JPEG at the given quality (1-100)
+func encodeProgressiveJPEG(img image.Image, quality int) ([]byte, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= 0 || height <= 0 || width >= 1<<16 || height >= 1<<16 {
+		return nil, fmt.Errorf("cannot encode a %dx%d image as JPEG", width, height)
+	}
+
+	rgba, ok := img.(*image.RGBA)
+	if !ok || bounds.Min != (image.Point{}) {
+		rgba = image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	}
+
+	quant := scaledQuant(quality)
+	components := transformYCbCr(rgba, quant)
+
+	var out bytes.Buffer
+	out.Write([]byte{0xff, 0xd8}) // SOI
+
+	// DQT
+	out.Write([]byte{0xff, 0xdb, 0x00, 2 + 2*65})
+	for i := range quant {
+		out.WriteByte(byte(i))
+		out.Write(quant[i][:])
+	}
+
+	// SOF2: 8-bit samples, Y sampled 2x2 and Cb, Cr 1x1 per MCU
+	out.Write([]byte{0xff, 0xc2, 0x00, 17, 8,
+		byte(height >> 8), byte(height), byte(width >> 8), byte(width), 3,
+		1, 0x22, 0,
+		2, 0x11, 1,
+		3, 0x11, 1,
+	})
+
+	// DHT: DC tables 0 (luminance) and 1 (chrominance)
+	var dcCodes [2][256]huffmanCode
+	for i, spec := range jpegDCHuffman {
+		writeDHT(&out, 0, i, spec)
+		dcCodes[i] = huffmanCodes(spec)
+	}
+
+	w := &jpegBitWriter{out: &out}
+	for _, scan := range progressiveScans {
+		// AC scans define their own table, built from a first pass that only counts symbols
+		var acCodes [256]huffmanCode
+		if scan.ss > 0 {
+			c := &components[scan.components[0]]
+			var freq [256]int
+			writeACScan(c, scan.ss, scan.se, func(symbol uint8, _, _ uint32) { freq[symbol]++ })
+			spec := optimalHuffman(freq)
+			writeDHT(&out, 1, c.table, spec)
+			acCodes = huffmanCodes(spec)
+		}
+
+		// SOS
+		out.Write([]byte{0xff, 0xda, 0x00, byte(6 + 2*len(scan.components)), byte(len(scan.components))})
+		for _, c := range scan.components {
+			table := byte(components[c].table)
+			out.WriteByte(byte(c + 1))
+			out.WriteByte(table<<4 | table)
+		}
+		out.Write([]byte{byte(scan.ss), byte(scan.se), 0x00})
+
+		if scan.ss == 0 {
+			writeDCScan(w, components, scan.components, &dcCodes)
+		} else {
+			writeACScan(&components[scan.components[0]], scan.ss, scan.se, func(symbol uint8, extra, n uint32) {
+				w.emitSymbol(&acCodes, symbol, extra, n)
+			})
+		}
+		w.flush()
+	}
+
+	out.Write([]byte{0xff, 0xd9}) // EOI
+	return out.Bytes(), nil
+}
+
+// scaledQuant scales the standard quantization tables for quality the way libjpeg does
+func scaledQuant(quality int) [2][64]byte {
+	if quality < 1 {
+		quality = 1
+	} else if quality > 100 {
+		quality = 100
+	}
+	scale := 200 - quality*2
+	if quality < 50 {
+		scale = 5000 / quality
+	}
+
+	var quant [2][64]byte
+	for i := range jpegQuant {
+		for j, base := range jpegQuant[i] {
+			value := (int(base)*scale + 50) / 100
+			if value < 1 {
+				value = 1
+			} else if value > 255 {
+				value = 255
+			}
+			quant[i][j] = byte(value)
+		}
+	}
+	return quant
+}
+
+// transformYCbCr converts the image to Y, Cb and Cr planes, with chroma averaged over 2x2
+// pixels, and returns the quantized DCT coefficients of each plane
+func transformYCbCr(img *image.RGBA, quant [2][64]byte) [3]jpegComponent {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	chromaWidth, chromaHeight := (width+1)/2, (height+1)/2
+
+	luma := make([]uint8, width*height)
+	cb := make([]int32, chromaWidth*chromaHeight)
+	cr := make([]int32, chromaWidth*chromaHeight)
+	for y := 0; y < height; y++ {
+		row := img.Pix[y*img.Stride:]
+		for x := 0; x < width; x++ {
+			p := row[x*4 : x*4+3]
+			yy, u, v := color.RGBToYCbCr(p[0], p[1], p[2])
+			luma[y*width+x] = yy
+			cb[(y/2)*chromaWidth+x/2] += int32(u)
+			cr[(y/2)*chromaWidth+x/2] += int32(v)
+		}
+	}
+
+	// Edge chroma samples may cover fewer than four pixels
+	chroma := func(sums []int32) []uint8 {
+		plane := make([]uint8, len(sums))
+		for cy := 0; cy < chromaHeight; cy++ {
+			rows := min(2, height-cy*2)
+			for cx := 0; cx < chromaWidth; cx++ {
+				n := int32(rows * min(2, width-cx*2))
+				plane[cy*chromaWidth+cx] = uint8((sums[cy*chromaWidth+cx] + n/2) / n)
+			}
+		}
+		return plane
+	}
+
+	mcusX, mcusY := (width+15)/16, (height+15)/16
+	return [3]jpegComponent{
+		forwardDCT(luma, width, height, mcusX*2, mcusY*2, 2, 0, quant[0]),
+		forwardDCT(chroma(cb), chromaWidth, chromaHeight, mcusX, mcusY, 1, 1, quant[1]),
+		forwardDCT(chroma(cr), chromaWidth, chromaHeight, mcusX, mcusY, 1, 1, quant[1]),
+	}
+}
+
+// dctCos holds cos((2x+1)uπ/16) scaled by the DCT normalisation C(u)/2, indexed [u][x]
+var dctCos = func() [8][8]float64 {
+	var table [8][8]float64
+	for u := 0; u < 8; u++ {
+		scale := 0.5
+		if u == 0 {
+			scale = 0.5 / math.Sqrt2
+		}
+		for x := 0; x < 8; x++ {
+			table[u][x] = scale * math.Cos(float64(2*x+1)*float64(u)*math.Pi/16)
+		}
+	}
+	return table
+}()
+
+// forwardDCT transforms a plane into quantized coefficients over a blocksX by blocksY grid,
+// repeating the last row and column of samples into the padding
+func forwardDCT(plane []uint8, width, height, blocksX, blocksY, sampling, table int, quant [64]byte) jpegComponent {
+	c := jpegComponent{
+		blocksX:      blocksX,
+		blocksY:      blocksY,
+		usedX:        (width + 7) / 8,
+		usedY:        (height + 7) / 8,
+		sampling:     sampling,
+		table:        table,
+		coefficients: make([]int16, blocksX*blocksY*64),
+	}
+
+	var samples, rows [64]float64
+	for by := 0; by < blocksY; by++ {
+		for bx := 0; bx < blocksX; bx++ {
+			for j := 0; j < 8; j++ {
+				y := min(by*8+j, height-1)
+				for i := 0; i < 8; i++ {
+					x := min(bx*8+i, width-1)
+					samples[j*8+i] = float64(plane[y*width+x]) - 128
+				}
+			}
+
+			// Separable 2D DCT: rows, then columns
+			for j := 0; j < 8; j++ {
+				for u := 0; u < 8; u++ {
+					sum := 0.0
+					for i := 0; i < 8; i++ {
+						sum += samples[j*8+i] * dctCos[u][i]
+					}
+					rows[j*8+u] = sum
+				}
+			}
+			block := c.block(bx, by)
+			for k := 0; k < 64; k++ {
+				u, v := zigzag[k]%8, zigzag[k]/8
+				sum := 0.0
+				for j := 0; j < 8; j++ {
+					sum += rows[j*8+u] * dctCos[v][j]
+				}
+				block[k] = int16(math.Round(sum / float64(quant[k])))
+			}
+		}
+	}
+	return c
+}
+
+// writeDHT writes a DHT segment defining one Huffman table of the given class (0 DC, 1 AC)
+func writeDHT(out *bytes.Buffer, class, id int, spec huffmanSpec) {
+	length := 2 + 17 + len(spec.symbols)
+	out.Write([]byte{0xff, 0xc4, byte(length >> 8), byte(length), byte(class<<4 | id)})
+	out.Write(spec.counts[:])
+	out.Write(spec.symbols)
+}
+
+// optimalHuffman builds a Huffman table of at most 16 bits per code for the symbol frequencies,
+// following JPEG Annex K.2. A reserved pseudo-symbol keeps any code from being all ones.
+func optimalHuffman(freq [256]int) huffmanSpec {
+	var counts [257]int
+	copy(counts[:], freq[:])
+	counts[256] = 1
+
+	var codeSize [257]int
+	var others [257]int
+	for i := range others {
+		others[i] = -1
+	}
+
+	for {
+		// Merge the two least frequent trees, preferring the highest symbols on ties
+		c1, c2 := -1, -1
+		for i, count := range counts {
+			if count > 0 && (c1 < 0 || count <= counts[c1]) {
+				c1 = i
+			}
+		}
+		for i, count := range counts {
+			if count > 0 && i != c1 && (c2 < 0 || count <= counts[c2]) {
+				c2 = i
+			}
+		}
+		if c2 < 0 {
+			break
+		}
+
+		counts[c1] += counts[c2]
+		counts[c2] = 0
+		for codeSize[c1]++; others[c1] >= 0; codeSize[c1]++ {
+			c1 = others[c1]
+		}
+		others[c1] = c2
+		for codeSize[c2]++; others[c2] >= 0; codeSize[c2]++ {
+			c2 = others[c2]
+		}
+	}
+
+	var lengths [33]int
+	for _, size := range codeSize {
+		if size > 0 {
+			lengths[size]++
+		}
+	}
+
+	// Shorten codes longer than 16 bits
+	for i := 32; i > 16; i-- {
+		for lengths[i] > 0 {
+			j := i - 2
+			for lengths[j] == 0 {
+				j--
+			}
+			lengths[i] -= 2
+			lengths[i-1]++
+			lengths[j+1] += 2
+			lengths[j]--
+		}
+	}
+
+	// Drop the pseudo-symbol, which has one of the longest codes
+	i := 16
+	for lengths[i] == 0 {
+		i--
+	}
+	lengths[i]--
+
+	var spec huffmanSpec
+	for length := 1; length <= 16; length++ {
+		spec.counts[length-1] = byte(lengths[length])
+	}
+	for size := 1; size <= 32; size++ {
+		for symbol := 0; symbol < 256; symbol++ {
+			if codeSize[symbol] == size {
+				spec.symbols = append(spec.symbols, byte(symbol))
+			}
+		}
+	}
+	return spec
+}
+
+// writeDCScan writes the DC coefficients of the given components interleaved by MCU
+func writeDCScan(w *jpegBitWriter, components [3]jpegComponent, scanComponents []int, codes *[2][256]huffmanCode) {
+	var predictors [3]int32
+	mcusX, mcusY := components[1].blocksX, components[1].blocksY
+	for my := 0; my < mcusY; my++ {
+		for mx := 0; mx < mcusX; mx++ {
+			for _, ci := range scanComponents {
+				c := &components[ci]
+				for by := 0; by < c.sampling; by++ {
+					for bx := 0; bx < c.sampling; bx++ {
+						dc := int32(c.block(mx*c.sampling+bx, my*c.sampling+by)[0])
+						size, extra := magnitude(dc - predictors[ci])
+						w.emitSymbol(&codes[c.table], uint8(size), extra, size)
+						predictors[ci] = dc
+					}
+				}
+			}
+		}
+	}
+}
+
+// writeACScan passes the symbols coding coefficients ss to se of one component to put, block by
+// block over the area the component covers. Blocks whose remaining coefficients are all zero
+// are gathered into end-of-band runs.
+func writeACScan(c *jpegComponent, ss, se int, put func(symbol uint8, extra, n uint32)) {
+	eobRun := uint32(0)
+	flushRun := func() {
+		if eobRun == 0 {
+			return
+		}
+		size := uint32(0)
+		for run := eobRun; run > 1; run >>= 1 {
+			size++
+		}
+		put(uint8(size<<4), eobRun&(1<<size-1), size)
+		eobRun = 0
+	}
+
+	for by := 0; by < c.usedY; by++ {
+		for bx := 0; bx < c.usedX; bx++ {
+			block := c.block(bx, by)
+			run := uint32(0)
+			for k := ss; k <= se; k++ {
+				if block[k] == 0 {
+					run++
+					continue
+				}
+				flushRun()
+				for ; run > 15; run -= 16 {
+					put(0xf0, 0, 0)
+				}
+				size, extra := magnitude(int32(block[k]))
+				put(uint8(run<<4|size), extra, size)
+				run = 0
+			}
+			if run > 0 {
+				if eobRun++; eobRun == maxEOBRun {
+					flushRun()
+				}
+			}
+		}
+	}
+	flushRun()
+}
@@ -0,0 +1,99 @@
+package screenshot
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// testImage returns a w by h image with smooth gradients and a hard edge, so both the low and
+// the high frequency scans carry data
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{
+				R: uint8(x * 255 / max(w, 1)),
+				G: uint8(y * 255 / max(h, 1)),
+				B: uint8((x + y) * 4),
+				A: 255,
+			}
+			if x > w/2 && y > h/2 {
+				c = color.RGBA{R: 20, G: 200, B: 40, A: 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// meanError returns the mean absolute difference per RGB channel between two images of the
+// same size
+func meanError(t *testing.T, a, b image.Image) float64 {
+	t.Helper()
+	bounds := a.Bounds()
+	var sum, n float64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			ar, ag, ab, _ := a.At(x, y).RGBA()
+			br, bg, bb, _ := b.At(x, y).RGBA()
+			for _, d := range [][2]uint32{{ar, br}, {ag, bg}, {ab, bb}} {
+				diff := float64(d[0]>>8) - float64(d[1]>>8)
+				if diff < 0 {
+					diff = -diff
+				}
+				sum += diff
+				n++
+			}
+		}
+	}
+	return sum / n
+}
+
+func TestEncodeProgressiveJPEG(t *testing.T) {
+	sizes := [][2]int{{1, 1}, {7, 9}, {16, 16}, {17, 33}, {100, 60}, {250, 37}}
+	qualities := []int{1, 75, 100}
+
+	for _, size := range sizes {
+		for _, quality := range qualities {
+			img := testImage(size[0], size[1])
+
+			data, err := encodeProgressiveJPEG(img, quality)
+			if err != nil {
+				t.Fatalf("%dx%d q%d: encode failed: %v", size[0], size[1], quality, err)
+			}
+			if !bytes.Contains(data, []byte{0xFF, 0xC2}) {
+				t.Errorf("%dx%d q%d: no SOF2 marker", size[0], size[1], quality)
+			}
+			if bytes.Contains(data, []byte{0xFF, 0xC0}) {
+				t.Errorf("%dx%d q%d: unexpected baseline SOF0 marker", size[0], size[1], quality)
+			}
+
+			decoded, err := jpeg.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("%dx%d q%d: decode failed: %v", size[0], size[1], quality, err)
+			}
+			if got := decoded.Bounds().Size(); got.X != size[0] || got.Y != size[1] {
+				t.Fatalf("%dx%d q%d: decoded size %dx%d", size[0], size[1], quality, got.X, got.Y)
+			}
+
+			var baseline bytes.Buffer
+			if err := jpeg.Encode(&baseline, img, &jpeg.Options{Quality: quality}); err != nil {
+				t.Fatalf("%dx%d q%d: baseline encode failed: %v", size[0], size[1], quality, err)
+			}
+			baselineDecoded, err := jpeg.Decode(&baseline)
+			if err != nil {
+				t.Fatalf("%dx%d q%d: baseline decode failed: %v", size[0], size[1], quality, err)
+			}
+
+			// Same quantization and subsampling as image/jpeg, so the error should match closely
+			progressiveErr := meanError(t, img, decoded)
+			baselineErr := meanError(t, img, baselineDecoded)
+			if progressiveErr > baselineErr+0.5 {
+				t.Errorf("%dx%d q%d: mean error %.2f, baseline %.2f", size[0], size[1], quality, progressiveErr, baselineErr)
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package screenshot
+
+import (
+	"context"
+	"log"
+	"net/url"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/chromedp"
+)
+
+// proxyServer returns the proxy address without credentials, as expected by --proxy-server
+func proxyServer(proxy string) string {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return proxy
+	}
+	u.User = nil
+	return u.String()
+}
+
+// enableProxyAuth answers proxy authentication challenges in the tab with the credentials
+// embedded in Config.Proxy. It does nothing when the proxy URL has no credentials.
+func (s *Screenshoter) enableProxyAuth(ctx context.Context) error {
+	u, err := url.Parse(s.Config.Proxy)
+	if err != nil || u.User == nil {
+		return nil
+	}
+	username := u.User.Username()
+	password, _ := u.User.Password()
+
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *fetch.EventRequestPaused:
+			go func() {
+				execCtx := cdp.WithExecutor(ctx, chromedp.FromContext(ctx).Target)
+				if err := fetch.ContinueRequest(ev.RequestID).Do(execCtx); err != nil {
+					log.Printf("Warning: failed to continue request through proxy: %v", err)
+				}
+			}()
+		case *fetch.EventAuthRequired:
+			go func() {
+				execCtx := cdp.WithExecutor(ctx, chromedp.FromContext(ctx).Target)
+				response := &fetch.AuthChallengeResponse{
+					Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+					Username: username,
+					Password: password,
+				}
+				if err := fetch.ContinueWithAuth(ev.RequestID, response).Do(execCtx); err != nil {
+					log.Printf("Warning: failed to answer proxy authentication challenge: %v", err)
+				}
+			}()
+		}
+	})
+
+	log.Printf("Answering proxy authentication challenges as %s", username)
+	return chromedp.Run(ctx, fetch.Enable().WithHandleAuthRequests(true))
+}
@@ -0,0 +1,88 @@
+package screenshot
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// tokenBucket allows rate events per second with a burst of one. Waiters reserve tokens in
+// the order they arrive, so a busy host is served first come, first served.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes a token and returns how long the caller must wait before using it
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > 1 {
+		b.tokens = 1
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// hostLimiter throttles captures with one token bucket per target host. A nil hostLimiter
+// does not limit anything.
+type hostLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	buckets map[string]*tokenBucket
+}
+
+// newHostLimiter returns a limiter allowing rate captures per second per host, or nil when rate is 0
+func newHostLimiter(rate float64) *hostLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &hostLimiter{rate: rate, buckets: make(map[string]*tokenBucket)}
+}
+
+// wait blocks until a capture of rawURL may start or ctx is done
+func (l *hostLimiter) wait(ctx context.Context, name, rawURL string) error {
+	if l == nil {
+		return nil
+	}
+
+	host := extractDomainFromURL(rawURL)
+	if parsed, err := url.Parse(rawURL); err == nil && parsed.Hostname() != "" {
+		host = parsed.Hostname()
+	}
+
+	l.mu.Lock()
+	bucket, ok := l.buckets[host]
+	if !ok {
+		now := time.Now()
+		bucket = &tokenBucket{rate: l.rate, tokens: 1, last: now}
+		l.buckets[host] = bucket
+	}
+	l.mu.Unlock()
+
+	delay := bucket.reserve(time.Now())
+	if delay <= 0 {
+		return nil
+	}
+
+	log.Printf("Rate limiting host %s: waiting %v before capturing %s", host, delay.Round(time.Millisecond), name)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,103 @@
+package screenshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/cdproto/inspector"
+	"github.com/chromedp/chromedp"
+)
+
+// Reconnection limits for Docker Chrome
+const (
+	maxDockerConnectAttempts = 5
+	dockerConnectBaseDelay   = 1 * time.Second
+	dockerConnectMaxDelay    = 16 * time.Second
+	maxViewportReconnects    = 2 // Times a viewport is retried after its Docker Chrome connection dropped
+	maxCrashRetries          = 2 // Times a viewport is retried in a fresh browser after its renderer crashed
+)
+
+// ErrRendererCrashed is returned when the renderer of a capture tab crashed, e.g. out of memory
+var ErrRendererCrashed = errors.New("renderer crashed")
+
+// remoteCaptureError marks an error from a capture that ran against Docker Chrome
+type remoteCaptureError struct {
+	err error
+}
+
+func (e *remoteCaptureError) Error() string {
+	return e.err.Error()
+}
+
+func (e *remoteCaptureError) Unwrap() error {
+	return e.err
+}
+
+// connectDockerChrome returns the debugging URL of a responsive Docker Chrome. While the
+// container is unreachable it is health-checked and restarted with exponential backoff,
+// giving up with a clear error after maxDockerConnectAttempts.
+func connectDockerChrome(ctx context.Context, cfg *config.Config) (string, error) {
+	delay := dockerConnectBaseDelay
+	var lastErr error
+	for attempt := 1; attempt <= maxDockerConnectAttempts; attempt++ {
+		dockerURL, err := startDockerChrome(cfg)
+		if err == nil {
+			return dockerURL, nil
+		}
+		lastErr = err
+		if attempt == maxDockerConnectAttempts {
+			break
+		}
+
+		log.Printf("Docker Chrome unavailable (attempt %d/%d): %v, retrying in %v",
+			attempt, maxDockerConnectAttempts, err, delay)
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > dockerConnectMaxDelay {
+			delay = dockerConnectMaxDelay
+		}
+	}
+	return "", fmt.Errorf("docker Chrome is gone, giving up after %d attempts: %w", maxDockerConnectAttempts, lastErr)
+}
+
+// dockerConnectionLost reports whether err came from a Docker Chrome capture whose browser
+// stopped responding, in which case the capture can be retried on a new connection
+func dockerConnectionLost(ctx context.Context, err error) bool {
+	var remoteErr *remoteCaptureError
+	if err == nil || ctx.Err() != nil || !errors.As(err, &remoteErr) {
+		return false
+	}
+	return checkChromeResponseFromContainer(1) != nil
+}
+
+// watchForCrash returns a context for the tab of ctx that is canceled with ErrRendererCrashed as
+// its cause as soon as the tab's renderer crashes, so the capture fails right away instead of
+// hanging until the URL times out
+func watchForCrash(ctx context.Context, urlConfig config.URLConfig, viewport config.Viewport) (context.Context, context.CancelFunc) {
+	crashCtx, cancel := context.WithCancelCause(ctx)
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		if _, ok := ev.(*inspector.EventTargetCrashed); ok {
+			log.Printf("ERROR: Renderer crashed while capturing %s (%s) at viewport %dx%d",
+				urlConfig.Name, urlConfig.URL, viewport.Width, viewport.Height)
+			cancel(ErrRendererCrashed)
+		}
+	})
+	return crashCtx, func() { cancel(context.Canceled) }
+}
+
+// crashError turns err into ErrRendererCrashed when it was caused by the renderer of the tab of
+// ctx crashing
+func crashError(ctx context.Context, err error) error {
+	if err != nil && !errors.Is(err, ErrRendererCrashed) && errors.Is(context.Cause(ctx), ErrRendererCrashed) {
+		return fmt.Errorf("%w: %v", ErrRendererCrashed, err)
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// RedirectHop is one step of the redirect chain recorded for a URL
+type RedirectHop struct {
+	URL    string `json:"url"`
+	Status int64  `json:"status"`
+}
+
+// recordRedirects follows navigations in the tab and records the HTTP redirect chain that
+// starts at the URL itself in the manifest. Login pages and other navigations are ignored.
+func (s *Screenshoter) recordRedirects(ctx context.Context, urlConfig config.URLConfig) error {
+	starts := make(map[network.RequestID]string)
+	chains := make(map[network.RequestID][]RedirectHop)
+
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			if ev.Type != network.ResourceTypeDocument || string(ev.RequestID) != string(ev.LoaderID) || ev.Request == nil {
+				return
+			}
+			if ev.RedirectResponse == nil {
+				starts[ev.RequestID] = ev.Request.URL
+				return
+			}
+			chains[ev.RequestID] = append(chains[ev.RequestID], RedirectHop{
+				URL:    ev.RedirectResponse.URL,
+				Status: ev.RedirectResponse.Status,
+			})
+		case *network.EventResponseReceived:
+			hops, ok := chains[ev.RequestID]
+			if !ok || ev.Type != network.ResourceTypeDocument || ev.Response == nil {
+				return
+			}
+			if sameURL(starts[ev.RequestID], urlConfig.URL) {
+				hops = append(hops, RedirectHop{URL: ev.Response.URL, Status: ev.Response.Status})
+				if s.Manifest.setRedirects(urlConfig.Name, hops) {
+					log.Printf("Recorded %d redirects for %s, ending at %s", len(hops)-1, urlConfig.Name, ev.Response.URL)
+				}
+			}
+			delete(chains, ev.RequestID)
+			delete(starts, ev.RequestID)
+		}
+	})
+
+	if err := chromedp.Run(ctx, network.Enable()); err != nil {
+		return fmt.Errorf("failed to enable network domain: %w", err)
+	}
+	return nil
+}
+
+// sameURL compares URLs the way Chrome reports them, where an empty path becomes "/"
+func sameURL(a, b string) bool {
+	normalize := func(raw string) string {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return raw
+		}
+		if u.Path == "" {
+			u.Path = "/"
+		}
+		return u.String()
+	}
+	return normalize(a) == normalize(b)
+}
@@ -0,0 +1,104 @@
+package screenshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// resumeFile is the record written into a viewport directory once all of its captures succeeded
+const resumeFile = "capture-complete.json"
+
+// markComplete records the images captured into viewportDir so a resumed run can skip it
+func (s *Screenshoter) markComplete(viewportDir string) error {
+	if !s.Config.Resume {
+		return nil
+	}
+	files := s.Manifest.filesIn(viewportDir)
+	if len(files) == 0 {
+		// Nothing was written here (e.g. skipUnchanged reused older images), so nothing to resume
+		return nil
+	}
+
+	data, err := json.MarshalIndent(files, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode resume record: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(viewportDir, resumeFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write resume record: %w", err)
+	}
+	return nil
+}
+
+// resumeViewport reports whether viewportDir holds a completed capture from an interrupted run
+// with the same RunLabel. The recorded images must all still exist and decode; they are then
+// added to the manifest as resumed. Otherwise the leftovers are removed so the viewport is
+// captured again from scratch.
+func (s *Screenshoter) resumeViewport(viewportDir string) bool {
+	if !s.Config.Resume {
+		return false
+	}
+
+	data, err := os.ReadFile(filepath.Join(viewportDir, resumeFile))
+	if errors.Is(err, os.ErrNotExist) {
+		s.clearViewportDir(viewportDir)
+		return false
+	} else if err != nil {
+		log.Printf("Warning: failed to read resume record in %s: %v", viewportDir, err)
+		return false
+	}
+
+	var files []ManifestFile
+	if err := json.Unmarshal(data, &files); err != nil || len(files) == 0 {
+		log.Printf("Warning: invalid resume record in %s, capturing again", viewportDir)
+		s.clearViewportDir(viewportDir)
+		return false
+	}
+	for _, file := range files {
+		if err := validateImage(file.Path); err != nil {
+			log.Printf("Cannot resume %s: %v", viewportDir, err)
+			s.clearViewportDir(viewportDir)
+			return false
+		}
+	}
+
+	for _, file := range files {
+		file.Resumed = true
+		s.Manifest.addFile(file)
+	}
+	log.Printf("Resumed %d images from %s", len(files), viewportDir)
+	return true
+}
+
+// clearViewportDir removes what an interrupted capture left in viewportDir, so stale images
+// do not sit next to the new ones
+func (s *Screenshoter) clearViewportDir(viewportDir string) {
+	entries, err := os.ReadDir(viewportDir)
+	if err != nil || len(entries) == 0 {
+		return
+	}
+	log.Printf("Discarding incomplete capture in %s", viewportDir)
+	if err := os.RemoveAll(viewportDir); err != nil {
+		log.Printf("Warning: failed to clear %s: %v", viewportDir, err)
+	}
+	if err := os.MkdirAll(viewportDir, 0755); err != nil {
+		log.Printf("Warning: failed to recreate %s: %v", viewportDir, err)
+	}
+}
+
+// validateImage checks that the file at path exists and decodes as an image
+func validateImage(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if _, _, err := image.Decode(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("%s does not decode: %w", path, err)
+	}
+	return nil
+}
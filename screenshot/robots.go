@@ -0,0 +1,212 @@
+package screenshot
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"screenshot-tool/config"
+)
+
+// maxRobotsBytes bounds how much of a robots.txt file is parsed
+const maxRobotsBytes = 512 * 1024
+
+// robotsRule is one Allow or Disallow line of a robots.txt group
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// robotsPolicy is the outcome of fetching a host's robots.txt for our user-agent
+type robotsPolicy struct {
+	rules       []robotsRule
+	disallowAll string // Set to the reason when the file could not be fetched and everything is disallowed
+}
+
+// filterRobots drops URLs that the robots.txt of their host disallows for Config.RobotsUserAgent,
+// recording them as skipped in the manifest. Each host's robots.txt is fetched once per run.
+func (s *Screenshoter) filterRobots(ctx context.Context, urls []config.URLConfig) []config.URLConfig {
+	if !s.Config.RespectRobots {
+		return urls
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	policies := make(map[string]*robotsPolicy) // scheme://host -> policy
+
+	var allowed []config.URLConfig
+	for _, urlConfig := range urls {
+		target, err := url.Parse(urlConfig.URL)
+		if err != nil || target.Host == "" {
+			log.Printf("Warning: cannot check robots.txt for %s: invalid URL", urlConfig.URL)
+			allowed = append(allowed, urlConfig)
+			continue
+		}
+
+		origin := target.Scheme + "://" + target.Host
+		policy, ok := policies[origin]
+		if !ok {
+			policy = fetchRobots(ctx, client, origin, s.Config.RobotsUserAgent)
+			policies[origin] = policy
+		}
+
+		if reason := policy.disallowed(target); reason != "" {
+			log.Printf("Skipping %s (%s): %s", urlConfig.Name, urlConfig.URL, reason)
+			s.Manifest.recordSkipped(urlConfig, reason)
+			continue
+		}
+		allowed = append(allowed, urlConfig)
+	}
+
+	log.Printf("robots.txt allows %d of %d URLs for user-agent %q", len(allowed), len(urls), s.Config.RobotsUserAgent)
+	return allowed
+}
+
+// fetchRobots downloads and parses origin's robots.txt. Following RFC 9309, a missing file
+// (4xx) allows everything and an unreachable one (5xx or a network error) disallows everything.
+func fetchRobots(ctx context.Context, client *http.Client, origin, userAgent string) *robotsPolicy {
+	robotsURL := origin + "/robots.txt"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return &robotsPolicy{disallowAll: fmt.Sprintf("robots.txt unreachable: %v", err)}
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("Warning: failed to fetch %s: %v", robotsURL, err)
+		return &robotsPolicy{disallowAll: fmt.Sprintf("robots.txt unreachable: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		log.Printf("Warning: %s returned %s", robotsURL, resp.Status)
+		return &robotsPolicy{disallowAll: fmt.Sprintf("robots.txt unreachable: %s", resp.Status)}
+	case resp.StatusCode >= 400:
+		log.Printf("No robots.txt at %s (%s), allowing all URLs", origin, resp.Status)
+		return &robotsPolicy{}
+	}
+
+	policy := &robotsPolicy{rules: parseRobots(io.LimitReader(resp.Body, maxRobotsBytes), userAgent)}
+	log.Printf("Loaded robots.txt for %s: %d rules apply to %q", origin, len(policy.rules), userAgent)
+	return policy
+}
+
+// parseRobots returns the rules of the groups matching userAgent, or of the "*" groups when
+// none match. A user-agent matches a group by its product token, case-insensitively.
+func parseRobots(r io.Reader, userAgent string) []robotsRule {
+	token := strings.ToLower(userAgent)
+	if idx := strings.IndexAny(token, "/ "); idx >= 0 {
+		token = token[:idx]
+	}
+
+	var matched, wildcard []robotsRule
+	var agents []string
+	inRules := false // A user-agent line after rules starts a new group
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if inRules {
+				agents = nil
+				inRules = false
+			}
+			agents = append(agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				// An empty Disallow allows everything and adds no rule
+				continue
+			}
+			rule := robotsRule{allow: key == "allow", pattern: value}
+			for _, agent := range agents {
+				if agent == token {
+					matched = append(matched, rule)
+				} else if agent == "*" {
+					wildcard = append(wildcard, rule)
+				}
+			}
+		}
+	}
+
+	if matched != nil {
+		return matched
+	}
+	return wildcard
+}
+
+// disallowed returns why target may not be captured, or "" when it may
+func (p *robotsPolicy) disallowed(target *url.URL) string {
+	if p.disallowAll != "" {
+		return p.disallowAll
+	}
+
+	path := target.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if target.RawQuery != "" {
+		path += "?" + target.RawQuery
+	}
+
+	// The longest matching pattern wins; Allow wins a tie
+	var best *robotsRule
+	for i := range p.rules {
+		rule := &p.rules[i]
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if best == nil || len(rule.pattern) > len(best.pattern) ||
+			(len(rule.pattern) == len(best.pattern) && rule.allow && !best.allow) {
+			best = rule
+		}
+	}
+
+	if best == nil || best.allow {
+		return ""
+	}
+	return fmt.Sprintf("disallowed by robots.txt (Disallow: %s)", best.pattern)
+}
+
+// robotsMatch reports whether path matches a robots.txt pattern, where "*" matches any
+// sequence of characters and a trailing "$" anchors the end of the path
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			// The last part must end the path
+			return strings.HasSuffix(rest, part)
+		}
+		idx := strings.Index(rest, part)
+		if idx < 0 {
+			return false
+		}
+		rest = rest[idx+len(part):]
+	}
+	return !anchored || rest == ""
+}
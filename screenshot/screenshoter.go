@@ -2,12 +2,16 @@ package screenshot
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 	"sync"
@@ -25,6 +29,9 @@ import (
 // Global mutex to synchronize Docker container operations
 var dockerMutex sync.Mutex
 
+// dockerStarted records whether this process launched the chrome container, guarded by dockerMutex
+var dockerStarted bool
+
 // findChromeExecutable attempts to locate the Chrome executable on the system
 func findChromeExecutable() (string, error) {
 	// Check for environment variable first
@@ -86,8 +93,9 @@ func findChromeExecutable() (string, error) {
 	return "", fmt.Errorf("could not find Chrome executable")
 }
 
-// startDockerChrome starts a Chrome instance in Docker if not already running
-func startDockerChrome() (string, error) {
+// startDockerChrome starts a Chrome instance in Docker if not already running, with the
+// configured shared memory size and memory limit
+func startDockerChrome(cfg *config.Config) (string, error) {
 	// Acquire mutex to prevent parallel container creation
 	dockerMutex.Lock()
 	defer dockerMutex.Unlock()
@@ -111,9 +119,11 @@ func startDockerChrome() (string, error) {
 		runningOutput, err := runningCmd.Output()
 
 		if err == nil && len(runningOutput) > 0 {
-			// Container is running, check if it responds
+			// Container is running, check if it was started with the same TLS flags and responds
 			log.Printf("Found existing Chrome container, checking if it's responsive")
-			if err := checkChromeResponseFromContainer(5); err == nil {
+			if !dockerChromeMatches(cfg) {
+				log.Printf("Existing Chrome container was started with other certificate or mixed content settings")
+			} else if err := checkChromeResponseFromContainer(5); err == nil {
 				log.Printf("Using existing Chrome container")
 				return "http://localhost:9222", nil
 			} else {
@@ -134,21 +144,19 @@ func startDockerChrome() (string, error) {
 
 	// Start a new chrome container with improved configuration
 	log.Printf("Starting a new Chrome container...")
-	cmd := exec.Command("docker", "run", "-d", "--rm", "--name", "chrome",
+	args := []string{"run", "-d", "--rm", "--name", "chrome",
 		"-p", "9222:9222", // Using standard port 9222 for chromedp/headless-shell
-		"--cap-add=SYS_ADMIN",              // Add capabilities needed for Chrome
-		"--shm-size=2g",                    // Increase shared memory size to 2GB
-		"--memory=4g",                      // Limit container memory to 4GB
-		"chromedp/headless-shell:latest",   // Use chromedp's official headless shell image
-		"--disable-web-security",           // Disable web security for testing
-		"--ignore-certificate-errors",      // Ignore SSL certificate errors
-		"--allow-running-insecure-content", // Allow loading insecure content
-		"--disable-dev-shm-usage",          // Don't use /dev/shm (prevents crashes)
-		"--no-sandbox")                     // No sandbox for container environment
+		"--cap-add=SYS_ADMIN",             // Add capabilities needed for Chrome
+		"--shm-size=" + cfg.DockerShmSize, // Shared memory for Chrome (default 2g)
+		"--memory=" + cfg.DockerMemory,    // Container memory limit (default 4g)
+		"chromedp/headless-shell:latest",  // Use chromedp's official headless shell image
+	}
+	cmd := exec.Command("docker", append(args, dockerChromeArgs(cfg)...)...)
 
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return "", fmt.Errorf("failed to start chrome container: %w, output: %s", err, string(output))
 	}
+	dockerStarted = true
 
 	// Wait for container to be ready with increased timeout
 	log.Printf("Waiting for Chrome container to be ready (this may take up to 20 seconds)...")
@@ -164,6 +172,7 @@ func startDockerChrome() (string, error) {
 				// Stop the container since it's not working
 				stopCmd := exec.Command("docker", "rm", "-f", "chrome")
 				stopCmd.Run() // Ignore errors
+				dockerStarted = false
 
 				return "", fmt.Errorf("chrome container started but not responding after retries: %v\nContainer logs: %s",
 					err, string(logs))
@@ -179,6 +188,62 @@ func startDockerChrome() (string, error) {
 	return "http://localhost:9222", nil
 }
 
+// dockerChromeArgs returns the Chrome flags the Docker Chrome container is started with
+func dockerChromeArgs(cfg *config.Config) []string {
+	args := []string{
+		"--disable-web-security",  // Disable web security for testing
+		"--disable-dev-shm-usage", // Don't use /dev/shm (prevents crashes)
+		"--no-sandbox",            // No sandbox for container environment
+	}
+	if cfg.IgnoreCertErrors {
+		args = append(args, "--ignore-certificate-errors")
+	}
+	if cfg.AllowInsecureContent {
+		args = append(args, "--allow-running-insecure-content")
+	}
+	return args
+}
+
+// dockerChromeMatches reports whether the running chrome container ignores certificate errors
+// and allows insecure content as cfg asks. Its arguments also hold the image's own entrypoint
+// flags, so only these two are compared.
+func dockerChromeMatches(cfg *config.Config) bool {
+	output, err := exec.Command("docker", "inspect", "-f", "{{json .Args}}", "chrome").Output()
+	if err != nil {
+		log.Printf("Warning: failed to inspect Chrome container: %v", err)
+		return false
+	}
+	var containerArgs []string
+	if err := json.Unmarshal(output, &containerArgs); err != nil {
+		log.Printf("Warning: failed to read Chrome container flags: %v", err)
+		return false
+	}
+	has := make(map[string]bool)
+	for _, arg := range containerArgs {
+		has[arg] = true
+	}
+	return has["--ignore-certificate-errors"] == cfg.IgnoreCertErrors &&
+		has["--allow-running-insecure-content"] == cfg.AllowInsecureContent
+}
+
+// stopDockerChrome stops the chrome container if it was started by this process
+func stopDockerChrome() error {
+	dockerMutex.Lock()
+	defer dockerMutex.Unlock()
+
+	if !dockerStarted {
+		return nil
+	}
+
+	log.Println("Stopping Chrome Docker container...")
+	if output, err := exec.Command("docker", "stop", "chrome").CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to stop chrome container: %w, output: %s", err, string(output))
+	}
+	dockerStarted = false
+	log.Println("Chrome Docker container stopped")
+	return nil
+}
+
 // checkChromeResponseFromContainer checks if Chrome is responding in the container
 // with the specified timeout in seconds
 func checkChromeResponseFromContainer(timeoutSeconds int) error {
@@ -217,14 +282,56 @@ func checkChromeResponseFromContainer(timeoutSeconds int) error {
 
 // Screenshoter handles the screenshot capturing logic
 type Screenshoter struct {
-	Config *config.Config
+	Config   *config.Config
+	Manifest *Manifest // Outcome of the most recent run
+	Sink     Sink      // Receives every image and artifact; a *MemorySink when Config.Output is "memory"
+	Events   io.Writer // Receives capture events as JSON lines when set, instead of Config.EventsFile
+
+	cache       *captureCache      // Content hashes from earlier runs, loaded by CaptureURLs when SkipUnchanged is set
+	postCapture *postCaptureRunner // Runs PostCaptureCommand on written images during CaptureURLs
+	timings     *captureTimings    // Time spent per capture phase during CaptureURLs when Timing is set
+	pauseGate   *pauseGate         // Holds back new captures in CaptureURLs between Pause and Resume
+
+	eventsMu   sync.Mutex
+	eventsFile *os.File // Config.EventsFile while CaptureURLs runs
+
+	sharedMu sync.Mutex
+	shared   *sharedBrowser // Browser kept alive across runs when KeepBrowserAlive is set
+
+	closeOnce sync.Once
+	closeErr  error
+	watching  bool // Set while Watch is running
 }
 
 // NewScreenshoter creates a new Screenshoter
 func NewScreenshoter(cfg *config.Config) *Screenshoter {
 	return &Screenshoter{
-		Config: cfg,
+		Config:    cfg,
+		Manifest:  &Manifest{StartedAt: time.Now()},
+		Sink:      newSink(cfg),
+		pauseGate: newPauseGate(),
+	}
+}
+
+// Close releases resources held by the Screenshoter, including the browser kept alive
+// across runs and the Docker Chrome container when this process started it. Embedders
+// should defer it after NewScreenshoter; calling it more than once is safe and returns
+// the first result.
+func (s *Screenshoter) Close() error {
+	s.closeOnce.Do(func() {
+		s.closeSharedBrowser()
+		s.closeErr = stopDockerChrome()
+	})
+	return s.closeErr
+}
+
+// browserContextID returns the browser context of the tab in ctx, so browser-wide cookie
+// calls target the isolated context instead of Chrome's default one
+func browserContextID(ctx context.Context) cdp.BrowserContextID {
+	if c := chromedp.FromContext(ctx); c != nil {
+		return c.BrowserContextID
 	}
+	return ""
 }
 
 // setCookiesAndLocalStorage sets cookies and localStorage items for a URL and refreshes the page
@@ -261,7 +368,7 @@ func (s *Screenshoter) setCookiesAndLocalStorage(ctx context.Context, urlConfig
 				len(urlConfig.Cookies), urlConfig.Name, defaultCookiesApplied)
 
 			// Get existing cookies first
-			existingCookies, err := storage.GetCookies().Do(ctx)
+			existingCookies, err := storage.GetCookies().WithBrowserContextID(browserContextID(ctx)).Do(ctx)
 			if err != nil {
 				log.Printf("ERROR: Failed to get existing cookies: %v", err)
 				return err
@@ -281,33 +388,28 @@ func (s *Screenshoter) setCookiesAndLocalStorage(ctx context.Context, urlConfig
 			cookiesChanged := false
 
 			for _, cookie := range urlConfig.Cookies {
-				// Extract domain from URL if not specified in cookie
-				domain := cookie.Domain
-				if domain == "" {
-					// Use the URL's domain
-					domain = extractDomainFromURL(urlConfig.URL)
-				}
-
-				// Set cookie path to root if not specified
-				path := cookie.Path
-				if path == "" {
-					path = "/"
-				}
+				// Default the domain to the URL's and the path to the root
+				param := cookieParam(cookie, urlConfig.URL, &expr)
 
 				// Check if this cookie already exists with the same value
-				key := cookie.Name + path + domain
+				key := param.Name + param.Path + param.Domain
 				if value, exists := existingCookieMap[key]; exists && value == cookie.Value {
 					log.Printf("Cookie %s already exists with the same value, skipping", cookie.Name)
 					continue
 				}
 
-				err := network.SetCookie(cookie.Name, cookie.Value).
-					WithExpires(&expr).
-					WithDomain(domain).
-					WithPath(path).
-					WithHTTPOnly(cookie.HTTPOnly).
-					WithSecure(cookie.Secure).
-					Do(ctx)
+				var err error
+				if cookie.ThirdParty {
+					err = setThirdPartyCookie(ctx, param)
+				} else {
+					err = network.SetCookie(param.Name, param.Value).
+						WithExpires(param.Expires).
+						WithDomain(param.Domain).
+						WithPath(param.Path).
+						WithHTTPOnly(param.HTTPOnly).
+						WithSecure(param.Secure).
+						Do(ctx)
+				}
 
 				if err != nil {
 					log.Printf("ERROR: Failed to set cookie %s: %v", cookie.Name, err)
@@ -365,7 +467,7 @@ func (s *Screenshoter) setCookiesAndLocalStorage(ctx context.Context, urlConfig
 		// Only refresh if needed
 		if needsRefresh || defaultCookiesApplied {
 			log.Printf("Refreshing page to ensure cookies and localStorage are applied")
-			if err := chromedp.Reload().Do(ctx); err != nil {
+			if err := s.reload().Do(ctx); err != nil {
 				return err
 			}
 
@@ -378,7 +480,7 @@ func (s *Screenshoter) setCookiesAndLocalStorage(ctx context.Context, urlConfig
 				}
 
 				// Verify cookies were actually set before continuing
-				cookies, err := storage.GetCookies().Do(ctx)
+				cookies, err := storage.GetCookies().WithBrowserContextID(browserContextID(ctx)).Do(ctx)
 				if err != nil {
 					log.Printf("ERROR: Failed to get cookies after setting DefaultCookies: %v", err)
 				} else {
@@ -389,7 +491,7 @@ func (s *Screenshoter) setCookiesAndLocalStorage(ctx context.Context, urlConfig
 					}
 				}
 
-				if err := chromedp.Reload().Do(ctx); err != nil {
+				if err := s.reload().Do(ctx); err != nil {
 					return err
 				}
 			}
@@ -401,63 +503,182 @@ func (s *Screenshoter) setCookiesAndLocalStorage(ctx context.Context, urlConfig
 		}
 
 		// Log cookies after setting our custom ones
-		return SaveCookiesToFile(ctx, urlConfig, stage, urlDir, viewport, screenshotType).Do(ctx)
+		return s.saveCookies(ctx, urlConfig, stage, urlDir, viewport, screenshotType).Do(ctx)
 	})
 }
 
 // CaptureURL captures screenshots for a given URL with all configured viewports
+// and records the outcome in the manifest
 func (s *Screenshoter) CaptureURL(ctx context.Context, urlConfig config.URLConfig) error {
-	viewportsCount := len(urlConfig.Viewports)
+	entry := ManifestEntry{
+		Name:   urlConfig.Name,
+		URL:    urlConfig.URL,
+		Tags:   urlConfig.Tags,
+		Status: StatusCaptured,
+	}
+
+	start := time.Now()
+	err := s.captureURL(ctx, urlConfig, &entry)
+	if err != nil {
+		entry.Status = StatusFailed
+		entry.Error = err.Error()
+	}
+	entry.Redirects = s.Manifest.takeRedirects(urlConfig.Name)
+	s.Manifest.addURL(entry)
+
+	images := 0
+	for _, dir := range append(entry.Dirs, entry.Dir) {
+		if dir != "" {
+			images += len(s.Manifest.filesIn(dir))
+		}
+	}
+	s.emit(Event{
+		Type:       EventURLDone,
+		Name:       urlConfig.Name,
+		URL:        urlConfig.URL,
+		Status:     entry.Status,
+		DurationMs: time.Since(start).Milliseconds(),
+		Images:     images,
+		Error:      entry.Error,
+	})
+
+	return err
+}
+
+// captureURL performs the capture for CaptureURL, filling in the manifest entry as it goes
+func (s *Screenshoter) captureURL(ctx context.Context, urlConfig config.URLConfig, entry *ManifestEntry) error {
+	variants := s.profileVariants(urlConfig)
+	viewportsCount := len(urlConfig.Viewports) * len(variants)
 	timeoutDuration := 120*time.Second + time.Duration(60*viewportsCount)*time.Second
 	ctx, cancel := context.WithTimeout(ctx, timeoutDuration)
 	defer cancel()
 
 	log.Printf("Set timeout of %v for URL %s with %d viewports", timeoutDuration, urlConfig.Name, viewportsCount)
 
-	timestamp := time.Now().Format("20060102-150405")
-	uniqueDirName := fmt.Sprintf("%s_%s", sanitizeFilename(urlConfig.Name), timestamp)
-
-	urlDir := filepath.Join(s.Config.OutputDir, uniqueDirName)
-	if err := os.MkdirAll(urlDir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory for URL %s: %w", urlConfig.Name, err)
+	// A run label replaces the timestamp so a resumed run finds the directories again. Second
+	// precision is enough: createURLDir goes through createUniqueDir, which suffixes the name
+	// when another run or a same-named URL started in the same second.
+	suffix := time.Now().Format("20060102-150405")
+	if s.Config.RunLabel != "" {
+		suffix = sanitizeFilename(s.Config.RunLabel)
+	}
+	uniqueDirName := fmt.Sprintf("%s_%s", sanitizeFilename(urlConfig.Name), suffix)
+
+	// The URL directory sits in the output directory, or in each viewport's directory in the
+	// viewport-first layout
+	urlDirs := make(map[string]string)
+	if s.Config.LayoutMode == config.LayoutViewportFirst {
+		for _, viewport := range urlConfig.Viewports {
+			dir, err := s.createURLDir(filepath.Join(s.outputDir(urlConfig), viewport.String()), uniqueDirName, urlConfig.Name)
+			if err != nil {
+				return err
+			}
+			urlDirs[viewport.String()] = dir
+			entry.Dirs = append(entry.Dirs, dir)
+		}
+	} else {
+		dir, err := s.createURLDir(s.outputDir(urlConfig), uniqueDirName, urlConfig.Name)
+		if err != nil {
+			return err
+		}
+		entry.Dir = dir
 	}
-
-	log.Printf("Created unique directory for %s: %s", urlConfig.Name, uniqueDirName)
 
 	viewproofNeeded := len(s.Config.ViewProof) > 0
 
 	var wg sync.WaitGroup
-	errChan := make(chan error, len(urlConfig.Viewports))
+	errChan := make(chan error, viewportsCount)
 	viewportSem := make(chan struct{}, 3) // Process up to 3 viewports in parallel
 
-	for i, viewport := range urlConfig.Viewports {
-		wg.Add(1)
-		go func(i int, viewport config.Viewport) {
-			defer wg.Done()
-
-			viewportSem <- struct{}{}
-			defer func() { <-viewportSem }()
+	for _, variant := range variants {
+		urlConfig := variant.urlConfig
+
+		// Directories are set up and resumed viewports found before any capture, so a responsive
+		// sweep can write into them
+		viewportDirs := make(map[string]string)
+		for _, viewport := range urlConfig.Viewports {
+			viewportDirName := viewport.String()
+			var viewportDir string
+			if s.Config.LayoutMode == config.LayoutViewportFirst {
+				viewportDir = filepath.Join(urlDirs[viewportDirName], variant.dirName)
+			} else {
+				viewportDir = filepath.Join(entry.Dir, variant.dirName, viewportDirName)
+			}
+			if s.writesFiles() {
+				if err := os.MkdirAll(viewportDir, 0755); err != nil {
+					errChan <- fmt.Errorf("failed to create directory for viewport %s: %w", viewportDirName, err)
+					continue
+				}
+			}
 
-			viewportDirName := fmt.Sprintf("%dx%d", viewport.Width, viewport.Height)
-			viewportDir := filepath.Join(urlDir, viewportDirName)
-			if err := os.MkdirAll(viewportDir, 0755); err != nil {
-				errChan <- fmt.Errorf("failed to create directory for viewport %s: %w", viewportDirName, err)
-				return
+			if s.resumeViewport(viewportDir) {
+				continue
 			}
+			viewportDirs[viewportDirName] = viewportDir
+		}
 
-			log.Printf("Capturing screenshots for %s at viewport %dx%d", urlConfig.Name, viewport.Width, viewport.Height)
+		sweepStart := time.Now()
+		swept := s.captureSweep(ctx, urlConfig, viewportDirs)
 
-			// Apply ViewProof to all viewports by removing the "i == 0" condition
-			if err := s.captureWithViewport(ctx, urlConfig, viewport, viewportDir, true, viewproofNeeded); err != nil {
-				errChan <- fmt.Errorf("failed to capture screenshots for %s at viewport %dx%d: %w",
-					urlConfig.Name, viewport.Width, viewport.Height, err)
-				return
+		for i, viewport := range urlConfig.Viewports {
+			viewportDir, ok := viewportDirs[viewport.String()]
+			if !ok {
+				continue
 			}
-		}(i, viewport)
+			wg.Add(1)
+			go func(i int, viewport config.Viewport, viewportDir string) {
+				defer wg.Done()
+
+				viewportSem <- struct{}{}
+				defer func() { <-viewportSem }()
+
+				// Apply ViewProof to all viewports by removing the "i == 0" condition
+				fullPage := inViewports(urlConfig.FullPageViewports, viewport)
+				sections := inViewports(urlConfig.SectionViewports, viewport)
+				withViewProof := viewproofNeeded && fullPage
+				if !swept[viewport.String()] || s.capturesBesideFullPage(urlConfig, sections, withViewProof) {
+					if urlConfig.ActiveProfile != "" {
+						log.Printf("Capturing screenshots for %s as profile %q at viewport %dx%d", urlConfig.Name, urlConfig.ActiveProfile, viewport.Width, viewport.Height)
+					} else {
+						log.Printf("Capturing screenshots for %s at viewport %dx%d", urlConfig.Name, viewport.Width, viewport.Height)
+					}
+
+					fullPage = fullPage && !swept[viewport.String()]
+					if err := s.captureWithViewport(ctx, urlConfig, viewport, viewportDir, fullPage, sections, withViewProof); err != nil {
+						errChan <- fmt.Errorf("failed to capture screenshots for %s at viewport %dx%d: %w",
+							urlConfig.Name, viewport.Width, viewport.Height, err)
+						return
+					}
+				} else {
+					s.emitCapture(EventCaptureSucceeded, urlConfig, viewport, viewportDir, sweepStart, nil)
+				}
+				if err := s.markComplete(viewportDir); err != nil {
+					log.Printf("Warning: %s at viewport %dx%d will be captured again on resume: %v",
+						urlConfig.Name, viewport.Width, viewport.Height, err)
+				}
+			}(i, viewport, viewportDir)
+		}
 	}
 
 	wg.Wait()
 
+	// A contact sheet still helps review the viewports that did succeed
+	if s.Config.ContactSheet && !s.Config.AuditOnly {
+		for _, variant := range variants {
+			dir, base := filepath.Join(entry.Dir, variant.dirName), sanitizeFilename(urlConfig.Name)
+			if s.Config.LayoutMode == config.LayoutViewportFirst {
+				// There is no single URL directory, so the sheet goes next to the viewport directories
+				dir, base = s.outputDir(urlConfig), uniqueDirName
+				if variant.dirName != "" {
+					base += "-" + variant.dirName
+				}
+			}
+			if err := s.writeContactSheet(variant.urlConfig, dir, base); err != nil {
+				log.Printf("Warning: failed to write contact sheet for %s: %v", urlConfig.Name, err)
+			}
+		}
+	}
+
 	select {
 	case err := <-errChan:
 		return err
@@ -466,22 +687,127 @@ func (s *Screenshoter) CaptureURL(ctx context.Context, urlConfig config.URLConfi
 	}
 }
 
-// captureWithViewport captures screenshots for a specific viewport size
-func (s *Screenshoter) captureWithViewport(ctx context.Context, urlConfig config.URLConfig, viewport config.Viewport, viewportDir string, captureViewports bool, withViewProof bool) error {
-	// Create browser options
+// outputDir returns the directory urlConfig's captures go under: its own when set, otherwise
+// Config.OutputDir
+func (s *Screenshoter) outputDir(urlConfig config.URLConfig) string {
+	if urlConfig.OutputDir != "" {
+		return urlConfig.OutputDir
+	}
+	return s.Config.OutputDir
+}
+
+// createURLDir creates the directory named base in parent for a URL's captures: a new one with
+// a numeric suffix if base is taken, or base itself when resuming. In memory the directory only
+// names the captures, so it is not created.
+func (s *Screenshoter) createURLDir(parent, base, name string) (string, error) {
+	dir := filepath.Join(parent, base)
+	if s.Config.Resume {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create directory for URL %s: %w", name, err)
+		}
+	} else if s.writesFiles() {
+		var err error
+		if dir, err = createUniqueDir(parent, base); err != nil {
+			return "", fmt.Errorf("failed to create directory for URL %s: %w", name, err)
+		}
+		log.Printf("Created unique directory for %s: %s", name, dir)
+	}
+	return dir, nil
+}
+
+// execAllocatorOptions builds the options used to launch a local Chrome for a viewport
+func (s *Screenshoter) execAllocatorOptions(viewport config.Viewport) []chromedp.ExecAllocatorOption {
 	opts := append(chromedp.DefaultExecAllocatorOptions[:],
 		chromedp.WindowSize(viewport.Width, viewport.Height),
 		chromedp.DisableGPU,
 		chromedp.NoSandbox,
-		chromedp.Headless,
-		chromedp.Flag("ignore-certificate-errors", true),
 	)
 
-	// Define context variables here
-	var allocCtx context.Context
-	var browserCtx context.Context
-	var cancelAlloc context.CancelFunc
-	var cancelBrowser context.CancelFunc
+	if s.Config.IgnoreCertErrors {
+		opts = append(opts, chromedp.Flag("ignore-certificate-errors", true))
+	}
+	if s.Config.AllowInsecureContent {
+		opts = append(opts, chromedp.Flag("allow-running-insecure-content", true))
+	}
+
+	if s.Config.Proxy != "" {
+		opts = append(opts, chromedp.ProxyServer(proxyServer(s.Config.Proxy)))
+		if s.Config.ProxyBypass != "" {
+			opts = append(opts, chromedp.Flag("proxy-bypass-list", s.Config.ProxyBypass))
+		}
+	}
+
+	if s.Config.HostResolverRules != "" {
+		opts = append(opts, chromedp.Flag("host-resolver-rules", s.Config.HostResolverRules))
+	}
+
+	if s.Config.ColorProfile != config.ColorProfileNone {
+		opts = append(opts, chromedp.Flag("force-color-profile", s.Config.ColorProfile))
+	}
+
+	if s.Config.Headless {
+		opts = append(opts, chromedp.Headless)
+	} else {
+		// DefaultExecAllocatorOptions enables headless, so it has to be switched off explicitly
+		opts = append(opts, chromedp.Flag("headless", false), chromedp.Flag("hide-scrollbars", false))
+	}
+
+	// User flags go last so they override the defaults above
+	for _, flag := range s.Config.ChromeFlags {
+		name, value := parseChromeFlag(flag)
+		opts = append(opts, chromedp.Flag(name, value))
+	}
+
+	return opts
+}
+
+// localOnlyOptions lists the configured options that only take effect when launching local Chrome
+func (s *Screenshoter) localOnlyOptions() []string {
+	var options []string
+	if s.Config.Proxy != "" {
+		options = append(options, "proxy")
+	}
+	if s.Config.HostResolverRules != "" {
+		options = append(options, "hostResolverRules")
+	}
+	if len(s.Config.ChromeFlags) > 0 {
+		options = append(options, "chromeFlags")
+	}
+	if s.Config.ColorProfile != config.ColorProfileNone && s.Config.ColorProfile != config.DefaultColorProfile {
+		options = append(options, "colorProfile")
+	}
+	return options
+}
+
+// parseChromeFlag splits a command-line style flag ("--key=value" or "--key") into the
+// name and value expected by chromedp.Flag; flags without a value are boolean switches
+func parseChromeFlag(flag string) (string, interface{}) {
+	flag = strings.TrimLeft(flag, "-")
+	if idx := strings.Index(flag, "="); idx >= 0 {
+		return flag[:idx], flag[idx+1:]
+	}
+	return flag, true
+}
+
+// slowMo spaces out actions by the configured step delay so a headed browser can be watched
+func (s *Screenshoter) slowMo(tasks []chromedp.Action) []chromedp.Action {
+	if s.Config.StepDelayMs <= 0 {
+		return tasks
+	}
+
+	delay := time.Duration(s.Config.StepDelayMs) * time.Millisecond
+	slowed := make([]chromedp.Action, 0, len(tasks)*2)
+	for _, task := range tasks {
+		slowed = append(slowed, task, chromedp.Sleep(delay))
+	}
+	return slowed
+}
+
+// newAllocator picks the Chrome implementation for the configured mode and creates its allocator.
+// The returned bool reports whether it connects to Docker Chrome rather than launching a local browser.
+func (s *Screenshoter) newAllocator(ctx context.Context, viewport config.Viewport) (context.Context, context.CancelFunc, bool, error) {
+	// Create browser options
+	opts := s.execAllocatorOptions(viewport)
 
 	// Determine which Chrome implementation to use based on the specified mode
 	switch s.Config.ChromeMode {
@@ -493,23 +819,23 @@ func (s *Screenshoter) captureWithViewport(ctx context.Context, urlConfig config
 			opts = append(opts, chromedp.ExecPath(execPath))
 
 			// Create allocator context with local Chrome
-			allocCtx, cancelAlloc = chromedp.NewExecAllocator(ctx, opts...)
-			defer cancelAlloc()
+			allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, opts...)
+			return allocCtx, cancelAlloc, false, nil
 		} else {
-			return fmt.Errorf("local Chrome mode specified but Chrome executable not found: %v", err)
+			return nil, nil, false, fmt.Errorf("local Chrome mode specified but Chrome executable not found: %v", err)
 		}
 
 	case "docker":
 		// Force use of Docker Chrome
 		log.Printf("Docker Chrome mode specified, starting or connecting to Docker Chrome...")
-		if dockerURL, err := startDockerChrome(); err == nil {
+		if dockerURL, err := connectDockerChrome(ctx, s.Config); err == nil {
 			// Use Docker Chrome
 			log.Printf("Using Docker Chrome at: %s", dockerURL)
 			// Use standard Chrome debugging protocol with chromedp/headless-shell
-			allocCtx, cancelAlloc = chromedp.NewRemoteAllocator(ctx, dockerURL)
-			defer cancelAlloc()
+			allocCtx, cancelAlloc := chromedp.NewRemoteAllocator(ctx, dockerURL)
+			return allocCtx, cancelAlloc, true, nil
 		} else {
-			return fmt.Errorf("docker Chrome mode specified but failed to start or connect to Docker Chrome: %v", err)
+			return nil, nil, false, fmt.Errorf("docker Chrome mode specified but failed to start or connect to Docker Chrome: %v", err)
 		}
 
 	default: // "auto" mode - try local, then Docker, then fallback
@@ -520,33 +846,221 @@ func (s *Screenshoter) captureWithViewport(ctx context.Context, urlConfig config
 			opts = append(opts, chromedp.ExecPath(execPath))
 
 			// Create allocator context with local Chrome
-			allocCtx, cancelAlloc = chromedp.NewExecAllocator(ctx, opts...)
-			defer cancelAlloc()
+			allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, opts...)
+			return allocCtx, cancelAlloc, false, nil
 		} else {
 			// Try Docker Chrome as fallback
 			log.Printf("Local Chrome not found: %v", err)
 			log.Printf("Attempting to use Docker Chrome...")
 
-			if dockerURL, err := startDockerChrome(); err == nil {
+			if dockerURL, err := connectDockerChrome(ctx, s.Config); err == nil {
 				// Use Docker Chrome
 				log.Printf("Using Docker Chrome at: %s", dockerURL)
 				// Use standard Chrome debugging protocol with chromedp/headless-shell
-				allocCtx, cancelAlloc = chromedp.NewRemoteAllocator(ctx, dockerURL)
-				defer cancelAlloc()
+				allocCtx, cancelAlloc := chromedp.NewRemoteAllocator(ctx, dockerURL)
+				return allocCtx, cancelAlloc, true, nil
 			} else {
 				// Fallback to default Chrome as last resort
 				log.Printf("Docker Chrome failed: %v", err)
 				log.Printf("Falling back to default Chrome settings")
 
-				allocCtx, cancelAlloc = chromedp.NewExecAllocator(ctx, opts...)
-				defer cancelAlloc()
+				allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, opts...)
+				return allocCtx, cancelAlloc, false, nil
+			}
+		}
+	}
+}
+
+// captureWithViewport captures screenshots for a specific viewport size
+func (s *Screenshoter) captureWithViewport(ctx context.Context, urlConfig config.URLConfig, viewport config.Viewport, viewportDir string, withFullPage, captureViewports, withViewProof bool) error {
+	start := time.Now()
+	s.emitCapture(EventCaptureStarted, urlConfig, viewport, viewportDir, start, nil)
+	reconnects, retries, crashes, rung := 0, 0, 0, 0
+	attemptConfig := urlConfig
+	for {
+		err := s.captureInBrowser(ctx, attemptConfig, viewport, viewportDir, withFullPage, captureViewports, withViewProof)
+		if reconnects < maxViewportReconnects && dockerConnectionLost(ctx, err) {
+			reconnects++
+			s.closeSharedBrowser()
+			log.Printf("Lost connection to Docker Chrome while capturing %s at viewport %dx%d, reconnecting (attempt %d/%d)",
+				urlConfig.Name, viewport.Width, viewport.Height, reconnects, maxViewportReconnects)
+			continue
+		}
+
+		// A crashed renderer is gone for good, so the viewport starts over in a fresh browser
+		if crashes < maxCrashRetries && ctx.Err() == nil && errors.Is(err, ErrRendererCrashed) {
+			crashes++
+			log.Printf("Retrying %s at viewport %dx%d in a fresh browser after a renderer crash (retry %d/%d)",
+				urlConfig.Name, viewport.Width, viewport.Height, crashes, maxCrashRetries)
+			s.Manifest.removeFiles(viewportDir, s.removeCapture)
+			continue
+		}
+
+		// Capture a failed viewport again, waiting differently each time along the retry ladder
+		if err != nil && rung < len(s.Config.RetryLadder) && ctx.Err() == nil && !errors.Is(err, errImageBudgetExceeded) {
+			rung++
+			attemptConfig = s.escalateRetry(urlConfig, rung)
+			log.Printf("Capturing %s at viewport %dx%d failed, retrying with %s (retry %d/%d): %v",
+				urlConfig.Name, viewport.Width, viewport.Height, retryStrategy(s.Config.RetryLadder[:rung]), rung, len(s.Config.RetryLadder), err)
+			s.Manifest.removeFiles(viewportDir, s.removeCapture)
+			continue
+		}
+
+		// Capture again when an image looked blank, keeping the last attempt's images either way.
+		// These retries climb the retry ladder too, as far as it goes.
+		if err == nil && retries < s.Config.SuspectRetries && ctx.Err() == nil && s.Manifest.hasSuspectFiles(viewportDir) {
+			retries++
+			if rung < len(s.Config.RetryLadder) {
+				rung++
+				attemptConfig = s.escalateRetry(urlConfig, rung)
+			}
+			log.Printf("Retrying %s at viewport %dx%d after a suspect image with %s (retry %d/%d)",
+				urlConfig.Name, viewport.Width, viewport.Height, retryStrategy(s.Config.RetryLadder[:rung]), retries, s.Config.SuspectRetries)
+			s.Manifest.removeFiles(viewportDir, s.removeCapture)
+			continue
+		}
+
+		if err == nil && rung > 0 {
+			log.Printf("Captured %s at viewport %dx%d with %s", urlConfig.Name, viewport.Width, viewport.Height, retryStrategy(s.Config.RetryLadder[:rung]))
+		}
+
+		if err != nil {
+			s.emitCapture(EventCaptureFailed, urlConfig, viewport, viewportDir, start, err)
+		} else {
+			s.emitCapture(EventCaptureSucceeded, urlConfig, viewport, viewportDir, start, nil)
+		}
+		return err
+	}
+}
+
+// escalateRetry returns urlConfig with the first rung steps of the retry ladder applied, each
+// on top of the ones before it
+func (s *Screenshoter) escalateRetry(urlConfig config.URLConfig, rung int) config.URLConfig {
+	for _, step := range s.Config.RetryLadder[:rung] {
+		switch step {
+		case config.RetryLongerDelay:
+			urlConfig.WaitStrategy = config.WaitDelay
+			urlConfig.Delay *= 3
+		case config.RetryNetworkIdle:
+			urlConfig.WaitStrategy = config.WaitNetworkIdle
+		case config.RetryNoImages:
+			urlConfig.BlockImages = true
+		}
+	}
+	return urlConfig
+}
+
+// retryStrategy describes the retry ladder steps in effect for logs, e.g. "longerDelay +
+// networkIdle", or "the same waits" before the first step
+func retryStrategy(steps []string) string {
+	if len(steps) == 0 {
+		return "the same waits"
+	}
+	return strings.Join(steps, " + ")
+}
+
+// captureInBrowser captures screenshots for a specific viewport size in a new browser tab.
+// Errors from a Docker Chrome tab are wrapped in remoteCaptureError.
+func (s *Screenshoter) captureInBrowser(ctx context.Context, urlConfig config.URLConfig, viewport config.Viewport, viewportDir string, withFullPage, captureViewports, withViewProof bool) (err error) {
+	browserCtx, closeTab, remote, err := s.openTab(ctx, viewport)
+	if err != nil {
+		return err
+	}
+	defer closeTab()
+	if remote {
+		defer func() {
+			if err != nil {
+				err = &remoteCaptureError{err: err}
 			}
+		}()
+	}
+
+	browserCtx, cancelCrash := watchForCrash(browserCtx, urlConfig, viewport)
+	defer cancelCrash()
+	defer func() { err = crashError(browserCtx, err) }()
+
+	if remote {
+		for _, option := range s.localOnlyOptions() {
+			log.Printf("Warning: %s only applies to local Chrome, ignoring it for Docker Chrome", option)
+		}
+	} else if s.Config.Proxy != "" {
+		if err := s.enableProxyAuth(browserCtx); err != nil {
+			return fmt.Errorf("failed to set up proxy authentication: %w", err)
+		}
+	}
+
+	s.handleDialogs(browserCtx, urlConfig)
+
+	// Start recording before the first navigation so the HAR covers every page load in the tab
+	var har *harRecorder
+	if s.Config.CaptureHAR {
+		if har, err = startHAR(browserCtx); err != nil {
+			return fmt.Errorf("failed to start HAR recording for %s: %w", urlConfig.Name, err)
 		}
 	}
 
-	// Create browser context
-	browserCtx, cancelBrowser = chromedp.NewContext(allocCtx, chromedp.WithLogf(log.Printf))
-	defer cancelBrowser()
+	if s.Config.RecordRedirects {
+		if err := s.recordRedirects(browserCtx, urlConfig); err != nil {
+			return fmt.Errorf("failed to start redirect recording for %s: %w", urlConfig.Name, err)
+		}
+	}
+
+	resetTab, err := s.prepareTab(browserCtx, urlConfig, viewport)
+	defer resetTab()
+	if err != nil {
+		return err
+	}
+
+	// Start from a clean slate before logging in or applying configured cookies and storage
+	if urlConfig.ClearCookies || urlConfig.ClearStorage {
+		if err := chromedp.Run(browserCtx, s.clearBrowserState(urlConfig)); err != nil {
+			return fmt.Errorf("failed to clear browser state for %s: %w", urlConfig.Name, err)
+		}
+	}
+
+	// Log in first so the session carries over to the captures in this tab
+	if urlConfig.Login != nil {
+		if err := s.performLogin(browserCtx, urlConfig); err != nil {
+			return fmt.Errorf("login failed for %s: %w", urlConfig.Name, err)
+		}
+	}
+
+	if urlConfig.Warmup {
+		s.warmup(browserCtx, urlConfig)
+	}
+
+	if s.Config.AuditOnly {
+		return s.auditPage(browserCtx, urlConfig, viewport, viewportDir)
+	}
+
+	skip, storeHashes, err := s.skipIfUnchanged(browserCtx, urlConfig, viewport, viewportDir)
+	if err != nil {
+		return fmt.Errorf("failed to check %s for changes: %w", urlConfig.Name, err)
+	}
+	if skip {
+		return nil
+	}
+	defer func() {
+		if err == nil {
+			storeHashes()
+		}
+	}()
+
+	// The filmstrip navigates on its own, so it is a separate set of frames from the captures below
+	if urlConfig.Filmstrip != nil {
+		if err := s.captureFilmstrip(browserCtx, urlConfig, viewport, viewportDir); err != nil {
+			return fmt.Errorf("failed to capture filmstrip for %s at viewport %dx%d: %w",
+				urlConfig.Name, viewport.Width, viewport.Height, err)
+		}
+	}
+
+	// The hero goes before the ViewProof capture so it is never taken from an annotated page
+	if s.Config.CaptureHero {
+		if err := s.captureHero(browserCtx, urlConfig, viewport, viewportDir); err != nil {
+			return fmt.Errorf("failed to capture hero screenshot for %s at viewport %dx%d: %w",
+				urlConfig.Name, viewport.Width, viewport.Height, err)
+		}
+	}
 
 	// If withViewProof is true, capture a full page screenshot with ViewProof first
 	if withViewProof {
@@ -555,30 +1069,227 @@ func (s *Screenshoter) captureWithViewport(ctx context.Context, urlConfig config
 		}
 	}
 
-	// Capture full page screenshot
-	if err := s.captureFullPageScreenshot(browserCtx, urlConfig, viewport, viewportDir); err != nil {
-		return fmt.Errorf("failed to capture full page screenshot for %s at viewport %dx%d: %w",
-			urlConfig.Name, viewport.Width, viewport.Height, err)
+	// Capture full page screenshot unless only the first viewport or only sections are wanted
+	fullPage := withFullPage
+	if !withFullPage {
+		log.Printf("Viewport %s is not in fullPageViewports for %s: skipping full page screenshot", viewport, urlConfig.Name)
+	} else if s.aboveFoldOnly(urlConfig) {
+		log.Printf("Above-the-fold mode for %s: skipping full page screenshot", urlConfig.Name)
+		fullPage = false
+	} else if s.Config.CaptureMode == config.CaptureModeSections {
+		log.Printf("Capture mode %q for %s: skipping full page screenshot", s.Config.CaptureMode, urlConfig.Name)
+		fullPage = false
+	}
+	captureFullPage := func(ctx context.Context) error {
+		if err := s.captureFullPageScreenshot(ctx, urlConfig, viewport, viewportDir); err != nil {
+			return fmt.Errorf("failed to capture full page screenshot for %s at viewport %dx%d: %w",
+				urlConfig.Name, viewport.Width, viewport.Height, err)
+		}
+		return nil
 	}
 
-	// Capture viewport screenshots if requested
-	if captureViewports {
-		if err := s.captureViewportScreenshots(browserCtx, urlConfig, viewport, viewportDir, true); err != nil {
+	// Capture viewport screenshots if requested. Above-the-fold mode still takes its first
+	// viewport here, whatever the capture mode.
+	sections := captureViewports
+	if !captureViewports {
+		log.Printf("Viewport %s is not in sectionViewports for %s: skipping viewport screenshots", viewport, urlConfig.Name)
+	} else if s.Config.CaptureMode == config.CaptureModeFull && !s.aboveFoldOnly(urlConfig) {
+		log.Printf("Capture mode %q for %s: skipping viewport screenshots", s.Config.CaptureMode, urlConfig.Name)
+		sections = false
+	}
+	captureSections := func(ctx context.Context) error {
+		if err := s.captureViewportScreenshots(ctx, urlConfig, viewport, viewportDir, true); err != nil {
 			return fmt.Errorf("failed to capture viewport screenshots for %s at viewport %dx%d: %w",
 				urlConfig.Name, viewport.Width, viewport.Height, err)
 		}
+		return nil
+	}
+
+	if fullPage && sections && s.Config.ParallelCaptureTypes {
+		if err := s.captureInParallel(browserCtx, urlConfig, viewport, remote, captureFullPage, captureSections); err != nil {
+			return err
+		}
+	} else {
+		if fullPage {
+			if err := captureFullPage(browserCtx); err != nil {
+				return err
+			}
+		}
+		if sections {
+			if err := captureSections(browserCtx); err != nil {
+				return err
+			}
+		}
+	}
+
+	if urlConfig.EachSelector != "" {
+		if err := s.captureEachSelector(browserCtx, urlConfig, viewport, viewportDir); err != nil {
+			return fmt.Errorf("failed to capture eachSelector elements for %s at viewport %dx%d: %w",
+				urlConfig.Name, viewport.Width, viewport.Height, err)
+		}
+	}
+
+	if err := s.saveArtifacts(browserCtx, urlConfig, viewportDir, har); err != nil {
+		return fmt.Errorf("failed to save artifacts for %s at viewport %dx%d: %w",
+			urlConfig.Name, viewport.Width, viewport.Height, err)
 	}
 
 	return nil
 }
 
-// SaveCookiesToFile saves all current cookies to a log file
+// prepareTab applies the URL's media emulation, zoom, matchMedia override and init scripts to the
+// tab of ctx. The returned function undoes the media emulation and is safe to call on error.
+func (s *Screenshoter) prepareTab(ctx context.Context, urlConfig config.URLConfig, viewport config.Viewport) (func(), error) {
+	reset := func() {}
+	emulated, err := s.emulateMedia(ctx, urlConfig)
+	if err != nil {
+		return reset, fmt.Errorf("failed to set up media emulation for %s: %w", urlConfig.Name, err)
+	}
+	if emulated {
+		reset = func() { resetMedia(ctx) }
+	}
+
+	if err := s.emulateZoom(ctx, viewport); err != nil {
+		return reset, fmt.Errorf("failed to set up zoom for %s: %w", urlConfig.Name, err)
+	}
+	if err := s.forceMediaMatch(ctx, urlConfig, viewport); err != nil {
+		return reset, fmt.Errorf("failed to install matchMedia override for %s: %w", urlConfig.Name, err)
+	}
+	if err := s.injectInitScripts(ctx, urlConfig); err != nil {
+		return reset, fmt.Errorf("failed to inject init scripts for %s: %w", urlConfig.Name, err)
+	}
+	if urlConfig.BlockImages {
+		if err := network.SetBlockedURLs(blockedImagePatterns).Do(ctx); err != nil {
+			return reset, fmt.Errorf("failed to block images for %s: %w", urlConfig.Name, err)
+		}
+	}
+	return reset, nil
+}
+
+// blockedImagePatterns are the request URLs URLConfig.BlockImages blocks, by file extension
+var blockedImagePatterns = []string{
+	"*.png", "*.png?*", "*.jpg", "*.jpg?*", "*.jpeg", "*.jpeg?*", "*.gif", "*.gif?*",
+	"*.webp", "*.webp?*", "*.avif", "*.avif?*", "*.svg", "*.svg?*", "*.ico", "*.ico?*",
+}
+
+// captureInParallel runs the full page capture in the tab of ctx while the sections are captured
+// in a second tab of the same browser context, which shares its cookies and storage. Both run to
+// the end and their errors are joined. remote tells whether the tabs belong to Docker Chrome.
+func (s *Screenshoter) captureInParallel(ctx context.Context, urlConfig config.URLConfig, viewport config.Viewport, remote bool, fullPage, sections func(context.Context) error) error {
+	tabCtx, cancelTab := chromedp.NewContext(ctx)
+	defer cancelTab()
+	tabCtx, cancelCrash := watchForCrash(tabCtx, urlConfig, viewport)
+	defer cancelCrash()
+
+	if err := s.sizeSharedTab(tabCtx, viewport); err != nil {
+		return err
+	}
+	// Proxy authentication is set up per tab, like in the first one
+	if !remote && s.Config.Proxy != "" {
+		if err := s.enableProxyAuth(tabCtx); err != nil {
+			return fmt.Errorf("failed to set up proxy authentication: %w", err)
+		}
+	}
+	s.handleDialogs(tabCtx, urlConfig)
+	resetTab, err := s.prepareTab(tabCtx, urlConfig, viewport)
+	defer resetTab()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Capturing full page and viewport screenshots of %s in parallel tabs", urlConfig.Name)
+	var wg sync.WaitGroup
+	var fullPageErr, sectionsErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		fullPageErr = fullPage(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		sectionsErr = crashError(tabCtx, sections(tabCtx))
+	}()
+	wg.Wait()
+
+	return errors.Join(fullPageErr, sectionsErr)
+}
+
+// navigate loads the URL, bounded by the configured navigation timeout. If the page does not
+// finish loading in time, whatever has rendered is captured as a "-partial" image (flagged in
+// the manifest) before the timeout error is returned, so failed runs still leave an artifact.
+func (s *Screenshoter) navigate(urlConfig config.URLConfig, viewport config.Viewport, viewportDir, kind string) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		timeout := time.Duration(s.Config.NavigationTimeoutMs) * time.Millisecond
+		navCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		err := s.navigateTo(urlConfig.URL).Do(navCtx)
+		if err == nil || ctx.Err() != nil || navCtx.Err() != context.DeadlineExceeded {
+			return err
+		}
+
+		log.Printf("Navigation to %s timed out after %v, capturing partial screenshot", urlConfig.URL, timeout)
+		var buf []byte
+		if capErr := chromedp.CaptureScreenshot(&buf).Do(ctx); capErr != nil {
+			log.Printf("ERROR: Failed to capture partial screenshot for %s: %v", urlConfig.Name, capErr)
+		} else {
+			timestamp := fileTimestamp()
+			filename := fmt.Sprintf("%s-%s-%dx%d-partial.%s", timestamp, kind, viewport.Width, viewport.Height, s.format(urlConfig))
+			path, writeErr := s.writeImage(urlConfig, viewport, kind, filepath.Join(viewportDir, filename), buf)
+			if writeErr != nil {
+				log.Printf("ERROR: Failed to write partial screenshot for %s: %v", urlConfig.Name, writeErr)
+			} else {
+				s.Manifest.updateFile(path, func(file *ManifestFile) { file.Partial = true })
+				log.Printf("Captured partial screenshot for %s: %s", urlConfig.Name, path)
+			}
+		}
+
+		return fmt.Errorf("navigation to %s timed out after %v: %w", urlConfig.URL, timeout, err)
+	})
+}
+
+// warmup loads the URL once in the tab and discards the result, so fonts, images and other
+// cacheable resources are already in the browser cache for the captures that follow. A failed
+// warmup is logged and the captures go ahead.
+func (s *Screenshoter) warmup(ctx context.Context, urlConfig config.URLConfig) {
+	log.Printf("Warming up cache for %s", urlConfig.Name)
+	start := time.Now()
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		navCtx, cancel := context.WithTimeout(ctx, time.Duration(s.Config.NavigationTimeoutMs)*time.Millisecond)
+		defer cancel()
+		if err := s.navigateTo(urlConfig.URL).Do(navCtx); err != nil {
+			return err
+		}
+		return s.waitForPage(urlConfig).Do(ctx)
+	}))
+	if err != nil {
+		log.Printf("Warning: warmup load of %s failed, capturing anyway: %v", urlConfig.Name, err)
+		return
+	}
+	log.Printf("Warmed up %s in %v", urlConfig.Name, time.Since(start))
+}
+
+// aboveFoldOnly reports whether only the first viewport should be captured for a URL
+func (s *Screenshoter) aboveFoldOnly(urlConfig config.URLConfig) bool {
+	return s.Config.AboveFoldOnly || urlConfig.AboveFoldOnly
+}
+
+// saveCookies logs the tab's cookies next to the screenshots when captures are written to disk
+func (s *Screenshoter) saveCookies(ctx context.Context, urlConfig config.URLConfig, stage string, urlDir string, viewport config.Viewport, screenshotType string) chromedp.ActionFunc {
+	if !s.writesFiles() {
+		return chromedp.ActionFunc(func(ctx context.Context) error { return nil })
+	}
+	return SaveCookiesToFile(ctx, urlConfig, stage, urlDir, viewport, screenshotType)
+}
+
+// SaveCookiesToFile saves all current cookies to a log file, and the page's localStorage and
+// sessionStorage to a storage log next to it
 func SaveCookiesToFile(ctx context.Context, urlConfig config.URLConfig, stage string, urlDir string, viewport config.Viewport, screenshotType string) chromedp.ActionFunc {
 	return chromedp.ActionFunc(func(ctx context.Context) error {
 		log.Printf("SaveCookiesToFile called for %s (stage: %s, type: %s)", urlConfig.Name, stage, screenshotType)
 
 		// Get all cookies
-		cookies, err := storage.GetCookies().Do(ctx)
+		cookies, err := storage.GetCookies().WithBrowserContextID(browserContextID(ctx)).Do(ctx)
 		if err != nil {
 			log.Printf("ERROR: Failed to get cookies: %v", err)
 			return err
@@ -602,6 +1313,8 @@ func SaveCookiesToFile(ctx context.Context, urlConfig config.URLConfig, stage st
 		}
 		log.Printf("Saved cookies to CSV successfully")
 
+		saveStorageLogs(ctx, urlConfig, stage, urlDir, viewport, screenshotType, timestamp)
+
 		log.Printf("Saved %d cookies to log files (viewport: %dx%d, type: %s, stage: %s)",
 			len(cookies), viewport.Width, viewport.Height, screenshotType, stage)
 		return nil
@@ -752,34 +1465,35 @@ func (s *Screenshoter) captureFullPageWithViewProof(ctx context.Context, urlConf
 	log.Printf("Capturing special full-proof screenshot with ViewProof data")
 
 	var buf []byte
-	timestamp := time.Now().Format("20060102-150405")
-	filename := fmt.Sprintf("%s-full-proof-%dx%d.%s", timestamp, viewport.Width, viewport.Height, s.Config.FileFormat)
+	timestamp := fileTimestamp()
+	filename := fmt.Sprintf("%s-full-proof-%dx%d.%s", timestamp, viewport.Width, viewport.Height, s.format(urlConfig))
 	filepath := filepath.Join(viewportDir, filename)
 
 	viewproofData := make(map[string]string)
 	var tasks []chromedp.Action
 
-	tasks = append(tasks, chromedp.Navigate(urlConfig.URL))
-	tasks = append(tasks, SaveCookiesToFile(ctx, urlConfig, "before", viewportDir, viewport, "full-proof"))
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseNavigation, s.navigate(urlConfig, viewport, viewportDir, "full-proof"))...)
+	storageTasks := []chromedp.Action{s.saveCookies(ctx, urlConfig, "before", viewportDir, viewport, "full-proof")}
 
 	// Apply cookies and localStorage BEFORE extracting ViewProof data
 	if len(urlConfig.Cookies) > 0 || len(urlConfig.LocalStorage) > 0 {
-		tasks = append(tasks, s.setCookiesAndLocalStorage(ctx, urlConfig, viewport, viewportDir, "after", "full-proof"))
+		storageTasks = append(storageTasks, s.setCookiesAndLocalStorage(ctx, urlConfig, viewport, viewportDir, "after", "full-proof"))
 
 		// Add explicit refresh after setting cookies/localStorage to ensure they're applied
-		tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
+		storageTasks = append(storageTasks, chromedp.ActionFunc(func(ctx context.Context) error {
 			log.Printf("Performing additional refresh to ensure cookies and localStorage are fully applied before ViewProof processing")
-			if err := chromedp.Reload().Do(ctx); err != nil {
+			if err := s.reload().Do(ctx); err != nil {
 				return err
 			}
 			// Wait for page to reload and stabilize
 			return chromedp.Sleep(1 * time.Second).Do(ctx)
 		}))
 	}
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseStorage, storageTasks...)...)
 
 	// Extract ViewProof data from cookies and localStorage AFTER setting them
 	tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
-		cookies, err := storage.GetCookies().Do(ctx)
+		cookies, err := storage.GetCookies().WithBrowserContextID(browserContextID(ctx)).Do(ctx)
 		if err != nil {
 			log.Printf("ERROR: Failed to get cookies for viewproof: %v", err)
 			return nil // Non-fatal error
@@ -806,13 +1520,8 @@ func (s *Screenshoter) captureFullPageWithViewProof(ctx context.Context, urlConf
 	}))
 
 	// Scroll to ensure lazy content is loaded
-	tasks = append(tasks,
-		chromedp.Sleep(time.Duration(urlConfig.Delay)*time.Millisecond),
-		chromedp.Evaluate(`window.scrollTo(0, document.body.scrollHeight)`, nil),
-		chromedp.Sleep(500*time.Millisecond),
-		chromedp.Evaluate(`window.scrollTo(0, 0)`, nil),
-		chromedp.Sleep(500*time.Millisecond),
-	)
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseWait, s.waitForPage(urlConfig))...)
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseScroll, s.lazyLoadScroll(urlConfig, viewportDir)...)...)
 
 	// Add ViewProof block
 	tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
@@ -831,11 +1540,11 @@ func (s *Screenshoter) captureFullPageWithViewProof(ctx context.Context, urlConf
 		return nil
 	}))
 
-	tasks = append(tasks, chromedp.Sleep(1*time.Second))
-	tasks = append(tasks, chromedp.Sleep(500*time.Millisecond))
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseWait, chromedp.Sleep(1*time.Second), chromedp.Sleep(500*time.Millisecond))...)
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseWait, s.waitBeforeCapture(urlConfig, viewport)...)...)
 
 	// Capture the screenshot
-	tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseCapture, chromedp.ActionFunc(func(ctx context.Context) error {
 		var metrics map[string]interface{}
 		if err := chromedp.Evaluate(`({
 			width: Math.max(document.body.scrollWidth, document.documentElement.scrollWidth),
@@ -848,38 +1557,18 @@ func (s *Screenshoter) captureFullPageWithViewProof(ctx context.Context, urlConf
 
 		// Limit height to prevent Chrome screenshot issues
 		height := int64(metrics["height"].(float64))
-		maxHeight := int64(16384)
-		if height > maxHeight {
-			log.Printf("Warning: Page height (%d) exceeds maximum allowed height (%d). Limiting height.",
-				height, maxHeight)
-			height = maxHeight
-		}
-
-		if err := emulation.SetDeviceMetricsOverride(width, height, 1, false).Do(ctx); err != nil {
-			return err
+		if s.aboveFoldOnly(urlConfig) {
+			height = int64(viewport.Height)
 		}
+		return s.captureFullHeight(ctx, urlConfig, width, height, &buf)
+	}))...)
 
-		err := chromedp.CaptureScreenshot(&buf).Do(ctx)
-		if err != nil {
-			// Try with smaller height if capture failed
-			if height > 8192 {
-				log.Printf("Screenshot capture failed, trying with reduced height...")
-				if err := emulation.SetDeviceMetricsOverride(width, 8192, 1, false).Do(ctx); err != nil {
-					return err
-				}
-				return chromedp.CaptureScreenshot(&buf).Do(ctx)
-			}
-			return err
-		}
-
-		return nil
-	}))
-
-	if err := chromedp.Run(ctx, tasks...); err != nil {
+	if err := chromedp.Run(ctx, s.slowMo(tasks)...); err != nil {
 		return err
 	}
 
-	if err := os.WriteFile(filepath, buf, 0644); err != nil {
+	filepath, err := s.writeImage(urlConfig, viewport, "full-proof", filepath, buf)
+	if err != nil {
 		return err
 	}
 
@@ -890,29 +1579,30 @@ func (s *Screenshoter) captureFullPageWithViewProof(ctx context.Context, urlConf
 // captureFullPageScreenshot captures a full page screenshot
 func (s *Screenshoter) captureFullPageScreenshot(ctx context.Context, urlConfig config.URLConfig, viewport config.Viewport, viewportDir string) error {
 	var buf []byte
-	timestamp := time.Now().Format("20060102-150405")
-	filename := fmt.Sprintf("%s-full-%dx%d.%s", timestamp, viewport.Width, viewport.Height, s.Config.FileFormat)
+	timestamp := fileTimestamp()
+	filename := fmt.Sprintf("%s-full-%dx%d.%s", timestamp, viewport.Width, viewport.Height, s.format(urlConfig))
 	filepath := filepath.Join(viewportDir, filename)
 
 	var tasks []chromedp.Action
 
-	tasks = append(tasks, chromedp.Navigate(urlConfig.URL))
-	tasks = append(tasks, SaveCookiesToFile(ctx, urlConfig, "before", viewportDir, viewport, "full page"))
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseNavigation, s.navigate(urlConfig, viewport, viewportDir, "full"))...)
+	storageTasks := []chromedp.Action{s.saveCookies(ctx, urlConfig, "before", viewportDir, viewport, "full page")}
 
 	// First apply cookies and localStorage
 	if len(urlConfig.Cookies) > 0 || len(urlConfig.LocalStorage) > 0 {
-		tasks = append(tasks, s.setCookiesAndLocalStorage(ctx, urlConfig, viewport, viewportDir, "after", "full page"))
+		storageTasks = append(storageTasks, s.setCookiesAndLocalStorage(ctx, urlConfig, viewport, viewportDir, "after", "full page"))
 
 		// Add explicit refresh after setting cookies/localStorage to ensure they're applied
-		tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
+		storageTasks = append(storageTasks, chromedp.ActionFunc(func(ctx context.Context) error {
 			log.Printf("Performing additional refresh to ensure cookies and localStorage are fully applied before screenshot capture")
-			if err := chromedp.Reload().Do(ctx); err != nil {
+			if err := s.reload().Do(ctx); err != nil {
 				return err
 			}
 			// Wait for page to reload and stabilize
 			return chromedp.Sleep(1 * time.Second).Do(ctx)
 		}))
 	}
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseStorage, storageTasks...)...)
 
 	// Then extract ViewProof data if needed
 	var viewproofData map[string]string
@@ -920,7 +1610,7 @@ func (s *Screenshoter) captureFullPageScreenshot(ctx context.Context, urlConfig
 		viewproofData = make(map[string]string)
 
 		tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
-			cookies, err := storage.GetCookies().Do(ctx)
+			cookies, err := storage.GetCookies().WithBrowserContextID(browserContextID(ctx)).Do(ctx)
 			if err != nil {
 				log.Printf("ERROR: Failed to get cookies for viewproof: %v", err)
 				return nil // Non-fatal error
@@ -947,48 +1637,14 @@ func (s *Screenshoter) captureFullPageScreenshot(ctx context.Context, urlConfig
 		}))
 	}
 
-	tasks = append(tasks,
-		chromedp.Sleep(time.Duration(urlConfig.Delay)*time.Millisecond),
-		chromedp.Evaluate(`window.scrollTo(0, document.body.scrollHeight)`, nil),
-		chromedp.Sleep(500*time.Millisecond),
-		chromedp.Evaluate(`window.scrollTo(0, 0)`, nil),
-		chromedp.Sleep(500*time.Millisecond),
-	)
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseWait, s.waitForPage(urlConfig))...)
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseScroll, s.lazyLoadScroll(urlConfig, viewportDir)...)...)
 
-	tasks = append(tasks, chromedp.Sleep(1*time.Second))
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseWait, chromedp.Sleep(1*time.Second))...)
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseWait, s.waitBeforeCapture(urlConfig, viewport)...)...)
 
-	tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
-		var metrics map[string]interface{}
-		if err := chromedp.Evaluate(`({
-			width: Math.max(document.body.scrollWidth, document.documentElement.scrollWidth),
-			height: Math.max(document.body.scrollHeight, document.documentElement.scrollHeight),
-		})`, &metrics).Do(ctx); err != nil {
-			return err
-		}
-
-		width := int64(viewport.Width)
-
-		height := int64(metrics["height"].(float64))
-		maxHeight := int64(16384)
-		if height > maxHeight {
-			log.Printf("Warning: Page height (%d) exceeds maximum allowed height (%d). Limiting height.",
-				height, maxHeight)
-			height = maxHeight
-		}
-
-		if err := emulation.SetDeviceMetricsOverride(width, height, 1, false).Do(ctx); err != nil {
-			return err
-		}
-
-		err := chromedp.CaptureScreenshot(&buf).Do(ctx)
-		if err != nil {
-			if height > 8192 {
-				log.Printf("Screenshot capture failed, trying with reduced height...")
-				if err := emulation.SetDeviceMetricsOverride(width, 8192, 1, false).Do(ctx); err != nil {
-					return err
-				}
-				return chromedp.CaptureScreenshot(&buf).Do(ctx)
-			}
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseCapture, chromedp.ActionFunc(func(ctx context.Context) error {
+		if err := s.captureFullPage(ctx, urlConfig, viewport, &buf); err != nil {
 			return err
 		}
 
@@ -1003,13 +1659,14 @@ func (s *Screenshoter) captureFullPageScreenshot(ctx context.Context, urlConfig
 		}
 
 		return nil
-	}))
+	}))...)
 
-	if err := chromedp.Run(ctx, tasks...); err != nil {
+	if err := chromedp.Run(ctx, s.slowMo(tasks)...); err != nil {
 		return err
 	}
 
-	if err := os.WriteFile(filepath, buf, 0644); err != nil {
+	filepath, err := s.writeImage(urlConfig, viewport, "full", filepath, buf)
+	if err != nil {
 		return err
 	}
 
@@ -1017,81 +1674,88 @@ func (s *Screenshoter) captureFullPageScreenshot(ctx context.Context, urlConfig
 	return nil
 }
 
+// captureFullPage captures the whole page as currently loaded in the tab at the viewport's width
+// into buf, or only the viewport's height with FullPageHeightMode "viewport"
+func (s *Screenshoter) captureFullPage(ctx context.Context, urlConfig config.URLConfig, viewport config.Viewport, buf *[]byte) error {
+	var metrics map[string]interface{}
+	if err := chromedp.Evaluate(`({
+		width: Math.max(document.body.scrollWidth, document.documentElement.scrollWidth),
+		height: Math.max(document.body.scrollHeight, document.documentElement.scrollHeight),
+	})`, &metrics).Do(ctx); err != nil {
+		return err
+	}
+
+	width := int64(viewport.Width)
+
+	height := int64(metrics["height"].(float64))
+	if s.Config.FullPageHeightMode == config.FullPageHeightViewport {
+		height = int64(viewport.Height)
+	}
+	return s.captureFullHeight(ctx, urlConfig, width, height, buf)
+}
+
 // captureViewportScreenshots captures screenshots divided by viewport
 func (s *Screenshoter) captureViewportScreenshots(ctx context.Context, urlConfig config.URLConfig, viewport config.Viewport, viewportDir string, captureViewports bool) error {
 	var pageHeight float64
-	timestamp := time.Now().Format("20060102-150405")
+	timestamp := fileTimestamp()
 
 	var tasks []chromedp.Action
 
-	tasks = append(tasks, chromedp.Navigate(urlConfig.URL))
-	tasks = append(tasks, SaveCookiesToFile(ctx, urlConfig, "before-viewport", viewportDir, viewport, "viewport"))
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseNavigation, s.navigate(urlConfig, viewport, viewportDir, "viewport"))...)
+	storageTasks := []chromedp.Action{s.saveCookies(ctx, urlConfig, "before-viewport", viewportDir, viewport, "viewport")}
 
 	if len(urlConfig.Cookies) > 0 || len(urlConfig.LocalStorage) > 0 {
-		tasks = append(tasks, s.setCookiesAndLocalStorage(ctx, urlConfig, viewport, viewportDir, "after-viewport", "viewport"))
+		storageTasks = append(storageTasks, s.setCookiesAndLocalStorage(ctx, urlConfig, viewport, viewportDir, "after-viewport", "viewport"))
 
 		// Add explicit refresh after setting cookies/localStorage to ensure they're applied
-		tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
+		storageTasks = append(storageTasks, chromedp.ActionFunc(func(ctx context.Context) error {
 			log.Printf("Performing additional refresh to ensure cookies and localStorage are fully applied before viewport screenshots")
-			if err := chromedp.Reload().Do(ctx); err != nil {
+			if err := s.reload().Do(ctx); err != nil {
 				return err
 			}
 			// Wait for page to reload and stabilize
 			return chromedp.Sleep(1 * time.Second).Do(ctx)
 		}))
 	}
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseStorage, storageTasks...)...)
 
-	tasks = append(tasks,
-		chromedp.Sleep(time.Duration(urlConfig.Delay)*time.Millisecond),
-		chromedp.Evaluate(`window.scrollTo(0, document.body.scrollHeight)`, nil),
-		chromedp.Sleep(500*time.Millisecond),
-		chromedp.Evaluate(`window.scrollTo(0, 0)`, nil),
-		chromedp.Sleep(500*time.Millisecond),
-	)
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseWait, s.waitForPage(urlConfig))...)
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseScroll, s.lazyLoadScroll(urlConfig, viewportDir)...)...)
 
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseWait, s.waitBeforeCapture(urlConfig, viewport)...)...)
 	tasks = append(tasks, chromedp.Evaluate(`Math.max(document.body.scrollHeight, document.documentElement.scrollHeight)`, &pageHeight))
 
-	if err := chromedp.Run(ctx, chromedp.Tasks(tasks)); err != nil {
+	if err := chromedp.Run(ctx, s.slowMo(tasks)...); err != nil {
 		return err
 	}
 
+	if urlConfig.ScrollToSelector != "" {
+		return s.captureAtSelector(ctx, urlConfig, viewport, viewportDir, timestamp)
+	}
+
 	viewportHeight := float64(viewport.Height)
 
 	if viewportHeight < 200 {
 		log.Printf("Warning: Small viewport height detected (%f). This might cause overlap issues.", viewportHeight)
 	}
 
-	viewportCount := int(math.Ceil(pageHeight / viewportHeight))
-
-	if viewportCount < 1 {
-		viewportCount = 1
-	}
+	sections := viewportSections(pageHeight, viewportHeight, s.aboveFoldOnly(urlConfig))
+	viewportCount := len(sections)
 
 	log.Printf("Page height: %f, Viewport height: %f, Will capture %d viewport screenshots",
 		pageHeight, viewportHeight, viewportCount)
 
-	if pageHeight <= viewportHeight || viewportCount == 1 {
+	if viewportCount == 1 {
 		var buf []byte
-		filename := fmt.Sprintf("%s-viewport-%dx%d-1.%s", timestamp, viewport.Width, viewport.Height, s.Config.FileFormat)
+		filename := fmt.Sprintf("%s-viewport-%dx%d-1.%s", timestamp, viewport.Width, viewport.Height, s.format(urlConfig))
 		filepath := filepath.Join(viewportDir, filename)
 
-		if err := chromedp.Run(ctx,
-			chromedp.Evaluate(`window.scrollTo(0, 0)`, nil),
-			chromedp.Sleep(300*time.Millisecond),
-
-			emulation.SetDeviceMetricsOverride(int64(viewport.Width), int64(viewport.Height), 1, false).
-				WithScreenOrientation(&emulation.ScreenOrientation{
-					Type:  emulation.OrientationTypePortraitPrimary,
-					Angle: 0,
-				}),
-
-			chromedp.Sleep(800*time.Millisecond),
-			chromedp.CaptureScreenshot(&buf),
-		); err != nil {
+		if err := chromedp.Run(ctx, s.sectionTasks(urlConfig, viewport, `window.scrollTo(0, 0)`, &buf)...); err != nil {
 			return err
 		}
 
-		if err := os.WriteFile(filepath, buf, 0644); err != nil {
+		filepath, err := s.writeImage(urlConfig, viewport, "viewport", filepath, buf)
+		if err != nil {
 			return err
 		}
 
@@ -1099,11 +1763,44 @@ func (s *Screenshoter) captureViewportScreenshots(ctx context.Context, urlConfig
 		return nil
 	}
 
+	capture := func(i int, scrollPos float64) ([]byte, float64, error) {
+		var buf []byte
+		var scrollY float64
+		scrollScript := fmt.Sprintf(`window.scrollTo({top: %f, left: 0, behavior: 'instant'})`, scrollPos)
+		tasks := append(s.sectionTasks(urlConfig, viewport, scrollScript, &buf), chromedp.Evaluate(`window.scrollY`, &scrollY))
+		if err := chromedp.Run(ctx, tasks...); err != nil {
+			return nil, 0, err
+		}
+		return buf, scrollY, nil
+	}
+	write := func(i int, buf []byte) error {
+		filename := fmt.Sprintf("%s-viewport-%dx%d-%d.%s", timestamp, viewport.Width, viewport.Height, i+1, s.format(urlConfig))
+		filepath, err := s.writeImage(urlConfig, viewport, "viewport", filepath.Join(viewportDir, filename), buf)
+		if err != nil {
+			return err
+		}
+		log.Printf("Captured viewport screenshot for %s: %s", urlConfig.Name, filepath)
+		return nil
+	}
+	return s.captureSections(urlConfig.Name, sections, viewportHeight, capture, write)
+}
+
+// captureSections captures every section with capture, which scrolls the tab to a position and
+// returns the screenshot with the scroll position it was taken at, and hands each one to write.
+// Sections share the tab's scroll position, so with CropSectionOverlap they are captured one
+// after another and cropped by the position actually captured; otherwise up to 4 run at a time.
+func (s *Screenshoter) captureSections(name string, sections []viewportSection, viewportHeight float64,
+	capture func(i int, scrollPos float64) ([]byte, float64, error), write func(i int, buf []byte) error) error {
+	parallel := 4
+	if s.Config.CropSectionOverlap {
+		parallel = 1
+	}
+
 	var wg sync.WaitGroup
-	errChan := make(chan error, viewportCount)
-	vpSem := make(chan struct{}, 4) // Process up to 4 viewport sections in parallel
+	errChan := make(chan error, len(sections))
+	vpSem := make(chan struct{}, parallel)
 
-	for i := 0; i < viewportCount; i++ {
+	for i := range sections {
 		wg.Add(1)
 		go func(i int) {
 			defer wg.Done()
@@ -1111,42 +1808,32 @@ func (s *Screenshoter) captureViewportScreenshots(ctx context.Context, urlConfig
 			vpSem <- struct{}{}
 			defer func() { <-vpSem }()
 
-			scrollPos := float64(i) * viewportHeight
-
-			if i == viewportCount-1 && scrollPos+viewportHeight > pageHeight {
-				scrollPos = pageHeight - viewportHeight
-				if scrollPos < 0 {
-					scrollPos = 0
-				}
-			}
-
-			filename := fmt.Sprintf("%s-viewport-%dx%d-%d.%s", timestamp, viewport.Width, viewport.Height, i+1, s.Config.FileFormat)
-			filepath := filepath.Join(viewportDir, filename)
-
-			var buf []byte
-			if err := chromedp.Run(ctx,
-				chromedp.Evaluate(fmt.Sprintf(`window.scrollTo({top: %f, left: 0, behavior: 'instant'})`, scrollPos), nil),
-				chromedp.Sleep(300*time.Millisecond),
-
-				emulation.SetDeviceMetricsOverride(int64(viewport.Width), int64(viewport.Height), 1, false).
-					WithScreenOrientation(&emulation.ScreenOrientation{
-						Type:  emulation.OrientationTypePortraitPrimary,
-						Angle: 0,
-					}),
-
-				chromedp.Sleep(800*time.Millisecond),
-				chromedp.CaptureScreenshot(&buf),
-			); err != nil {
+			buf, scrollY, err := capture(i, sections[i].scrollPos)
+			if err != nil {
 				errChan <- err
 				return
 			}
 
-			if err := os.WriteFile(filepath, buf, 0644); err != nil {
-				errChan <- err
-				return
+			if s.Config.CropSectionOverlap {
+				if scrollY != sections[i].scrollPos {
+					log.Printf("Warning: section %d of %s was captured at %.0fpx instead of %.0fpx",
+						i+1, name, scrollY, sections[i].scrollPos)
+				}
+				// The section should start where the one before ended; anything above that repeats it
+				if overlap := float64(i)*viewportHeight - scrollY; overlap > 0 {
+					cropped, err := cropTop(buf, overlap/viewportHeight)
+					if err != nil {
+						errChan <- fmt.Errorf("failed to crop section overlap for %s: %w", name, err)
+						return
+					}
+					log.Printf("Cropped %.0fpx of overlap off section %d of %s", overlap, i+1, name)
+					buf = cropped
+				}
 			}
 
-			log.Printf("Captured viewport screenshot for %s: %s", urlConfig.Name, filepath)
+			if err := write(i, buf); err != nil {
+				errChan <- err
+			}
 		}(i)
 	}
 
@@ -1160,6 +1847,97 @@ func (s *Screenshoter) captureViewportScreenshots(ctx context.Context, urlConfig
 	}
 }
 
+// viewportSection is one viewport-high section of a page, captured with the page scrolled to
+// scrollPos
+type viewportSection struct {
+	scrollPos float64
+}
+
+// viewportSections splits a page into the sections captured one viewport at a time, a single one
+// for pages no taller than the viewport or with aboveFoldOnly. The last section is scrolled back
+// to end at the page bottom, so cropping each section's overlap off tiles the page height exactly.
+func viewportSections(pageHeight, viewportHeight float64, aboveFoldOnly bool) []viewportSection {
+	count := int(math.Ceil(pageHeight / viewportHeight))
+	if count < 1 || aboveFoldOnly {
+		count = 1
+	}
+
+	sections := make([]viewportSection, count)
+	for i := range sections {
+		scrollPos := float64(i) * viewportHeight
+		if count > 1 && i == count-1 && scrollPos+viewportHeight > pageHeight {
+			scrollPos = math.Max(pageHeight-viewportHeight, 0)
+		}
+		sections[i] = viewportSection{scrollPos: scrollPos}
+	}
+	return sections
+}
+
+// sectionTasks scrolls with scrollScript and captures the viewport there into buf
+func (s *Screenshoter) sectionTasks(urlConfig config.URLConfig, viewport config.Viewport, scrollScript string, buf *[]byte) []chromedp.Action {
+	var tasks []chromedp.Action
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseScroll, chromedp.Evaluate(scrollScript, nil))...)
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseWait, chromedp.Sleep(300*time.Millisecond))...)
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseCapture,
+		emulation.SetDeviceMetricsOverride(int64(viewport.Width), int64(viewport.Height), 1, false).
+			WithScreenOrientation(&emulation.ScreenOrientation{
+				Type:  emulation.OrientationTypePortraitPrimary,
+				Angle: 0,
+			}),
+	)...)
+	tasks = append(tasks, s.timed(urlConfig, viewport, phaseWait, chromedp.Sleep(800*time.Millisecond))...)
+	return append(tasks, s.timed(urlConfig, viewport, phaseCapture, chromedp.CaptureScreenshot(buf))...)
+}
+
+// captureAtSelector scrolls the configured element (inside FrameSelector's frame, if set) to the center
+// of the viewport and captures a single screenshot there
+func (s *Screenshoter) captureAtSelector(ctx context.Context, urlConfig config.URLConfig, viewport config.Viewport, viewportDir, timestamp string) error {
+	var buf []byte
+	var found bool
+	filename := fmt.Sprintf("%s-viewport-%dx%d-selector.%s", timestamp, viewport.Width, viewport.Height, s.format(urlConfig))
+	filepath := filepath.Join(viewportDir, filename)
+
+	scrollScript := fmt.Sprintf(`
+	(function() {
+		const el = %s.document.querySelector("%s");
+		if (!el) {
+			return false;
+		}
+		el.scrollIntoView({block: 'center', inline: 'nearest', behavior: 'instant'});
+		return true;
+	})()`, frameWindowJS(urlConfig), escapeJSString(urlConfig.ScrollToSelector))
+
+	if err := chromedp.Run(ctx,
+		emulation.SetDeviceMetricsOverride(int64(viewport.Width), int64(viewport.Height), 1, false).
+			WithScreenOrientation(&emulation.ScreenOrientation{
+				Type:  emulation.OrientationTypePortraitPrimary,
+				Angle: 0,
+			}),
+		chromedp.Evaluate(scrollScript, &found),
+	); err != nil {
+		return err
+	}
+
+	if !found {
+		return fmt.Errorf("scrollToSelector %q not found on %s", urlConfig.ScrollToSelector, urlConfig.URL)
+	}
+
+	if err := chromedp.Run(ctx,
+		chromedp.Sleep(800*time.Millisecond),
+		chromedp.CaptureScreenshot(&buf),
+	); err != nil {
+		return err
+	}
+
+	filepath, err := s.writeImage(urlConfig, viewport, "selector", filepath, buf)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Captured viewport screenshot centered on %q for %s: %s", urlConfig.ScrollToSelector, urlConfig.Name, filepath)
+	return nil
+}
+
 // extractDomainFromURL extracts a domain name from a URL for cookie setting
 func extractDomainFromURL(url string) string {
 	if strings.HasPrefix(url, "http://") {
@@ -1388,36 +2166,235 @@ func (s *Screenshoter) createViewProof(viewproofData map[string]string, forceful
 	return script, css
 }
 
+// filterURLs returns the configured URLs that pass the include/exclude patterns and run tags.
+// Patterns are matched against both the URL name and the URL itself; exclude takes precedence.
+// Filtered-out URLs are recorded as skipped in the manifest.
+func (s *Screenshoter) filterURLs() ([]config.URLConfig, error) {
+	if s.Config.IncludePattern == "" && s.Config.ExcludePattern == "" && len(s.Config.RunTags) == 0 {
+		return s.Config.URLs, nil
+	}
+
+	var includeRe, excludeRe *regexp.Regexp
+	var err error
+	if s.Config.IncludePattern != "" {
+		if includeRe, err = regexp.Compile(s.Config.IncludePattern); err != nil {
+			return nil, fmt.Errorf("invalid include pattern: %w", err)
+		}
+	}
+	if s.Config.ExcludePattern != "" {
+		if excludeRe, err = regexp.Compile(s.Config.ExcludePattern); err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern: %w", err)
+		}
+	}
+
+	matches := func(re *regexp.Regexp, urlConfig config.URLConfig) bool {
+		return re.MatchString(urlConfig.Name) || re.MatchString(urlConfig.URL)
+	}
+
+	var urls []config.URLConfig
+	for _, urlConfig := range s.Config.URLs {
+		reason := ""
+		switch {
+		case excludeRe != nil && matches(excludeRe, urlConfig):
+			reason = "matches exclude pattern"
+		case includeRe != nil && !matches(includeRe, urlConfig):
+			reason = "does not match include pattern"
+		case len(s.Config.RunTags) > 0 && !urlConfig.HasAnyTag(s.Config.RunTags):
+			reason = fmt.Sprintf("has none of the run tags %v", s.Config.RunTags)
+		}
+
+		if reason != "" {
+			log.Printf("Skipping %s (%s): %s", urlConfig.Name, urlConfig.URL, reason)
+			s.Manifest.recordSkipped(urlConfig, reason)
+			continue
+		}
+		urls = append(urls, urlConfig)
+	}
+
+	log.Printf("URL filters selected %d of %d URLs", len(urls), len(s.Config.URLs))
+	return urls, nil
+}
+
+// inViewports reports whether viewport is one of viewports, which when empty stands for all
+func inViewports(viewports []config.Viewport, viewport config.Viewport) bool {
+	if len(viewports) == 0 {
+		return true
+	}
+	for _, v := range viewports {
+		if v.String() == viewport.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// estimateImages returns the minimum number of images capturing urls will produce. Viewport
+// sections depend on page height, so each viewport counts as a single section.
+func (s *Screenshoter) estimateImages(urls []config.URLConfig) int {
+	if s.Config.AuditOnly {
+		return 0
+	}
+	total := 0
+	for _, urlConfig := range urls {
+		for _, viewport := range urlConfig.Viewports {
+			fullPage := inViewports(urlConfig.FullPageViewports, viewport)
+			perViewport := 0
+			if len(s.Config.ViewProof) > 0 && fullPage {
+				perViewport++
+			}
+			if s.Config.CaptureHero {
+				perViewport++
+			}
+			if inViewports(urlConfig.SectionViewports, viewport) && (s.aboveFoldOnly(urlConfig) || s.Config.CaptureMode != config.CaptureModeFull) {
+				perViewport++
+			}
+			if fullPage && !s.aboveFoldOnly(urlConfig) && s.Config.CaptureMode != config.CaptureModeSections {
+				perViewport++
+			}
+			total += perViewport * max(1, len(urlConfig.Profiles))
+		}
+	}
+	return total
+}
+
 // CaptureURLs captures screenshots for all URLs in configuration
 func (s *Screenshoter) CaptureURLs(ctx context.Context) error {
-	sem := make(chan struct{}, s.Config.Concurrency)
-	errChan := make(chan error, len(s.Config.URLs))
-	doneChan := make(chan struct{}, len(s.Config.URLs))
+	s.Manifest = &Manifest{StartedAt: time.Now()}
 
-	for _, urlConfig := range s.Config.URLs {
+	// The post-run command also runs after a failed pre-run command, to tear down whatever it
+	// did set up, and after an interrupted run
+	defer func() {
+		if err := runHook(context.WithoutCancel(ctx), "postRunCommand", s.Config.PostRunCommand); err != nil {
+			log.Printf("ERROR: %v", err)
+		}
+	}()
+	if err := runHook(ctx, "preRunCommand", s.Config.PreRunCommand); err != nil {
+		return err
+	}
+
+	urls, err := s.filterURLs()
+	if err != nil {
+		return err
+	}
+	urls = s.filterRobots(ctx, urls)
+
+	if s.Config.SkipUnchanged {
+		if s.cache, err = loadCaptureCache(s.Config.OutputDir); err != nil {
+			return err
+		}
+		defer func() {
+			if err := s.cache.save(); err != nil {
+				log.Printf("ERROR: %v", err)
+			}
+			s.cache = nil
+		}()
+	}
+
+	if s.Config.MaxImages > 0 {
+		if estimate := s.estimateImages(urls); estimate > s.Config.MaxImages {
+			return fmt.Errorf("%w: run would produce at least %d images, over maxImages %d",
+				errImageBudgetExceeded, estimate, s.Config.MaxImages)
+		}
+	}
+
+	if s.Config.EventsFile != "" && s.Events == nil {
+		if err := s.openEvents(); err != nil {
+			return err
+		}
+		defer s.closeEvents()
+	}
+
+	if s.postCapture, err = newPostCaptureRunner(ctx, s.Config); err != nil {
+		return err
+	}
+	defer func() { s.postCapture = nil }()
+
+	if s.Config.Timing {
+		s.timings = newCaptureTimings()
+		defer func() { s.timings = nil }()
+	}
+
+	// Reaching the image budget cancels the rest of the run
+	runCtx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
+
+	sem := make(chan struct{}, s.Config.Concurrency)
+	errChan := make(chan error, len(urls))
+	doneChan := make(chan struct{}, len(urls))
+	var budgetErr error
+	var budgetOnce sync.Once
+
+	// Each URL waits for its host's rate limit before taking a concurrency slot, so a throttled
+	// host does not hold up captures of other hosts
+	limiter := newHostLimiter(s.Config.PerHostRateLimit)
+	for _, urlConfig := range urls {
 		urlConfig := urlConfig // Create local copy for goroutine
-		sem <- struct{}{}
 
 		go func() {
-			defer func() {
-				<-sem
-				doneChan <- struct{}{}
-			}()
+			defer func() { doneChan <- struct{}{} }()
 
-			if err := s.CaptureURL(ctx, urlConfig); err != nil {
-				errChan <- fmt.Errorf("error capturing URL %s: %w", urlConfig.Name, err)
+			if err := limiter.wait(runCtx, urlConfig.Name, urlConfig.URL); err != nil {
+				return
+			}
+			select {
+			case sem <- struct{}{}:
+			case <-runCtx.Done():
+				return
+			}
+			defer func() { <-sem }()
+			// While paused, hold the slot so nothing else starts either
+			if err := s.pauseGate.wait(runCtx); err != nil {
+				return
+			}
+			if runCtx.Err() != nil {
+				return
+			}
+
+			if err := s.CaptureURL(runCtx, urlConfig); err != nil {
+				err = fmt.Errorf("error capturing URL %s: %w", urlConfig.Name, err)
+				if errors.Is(err, errImageBudgetExceeded) {
+					budgetOnce.Do(func() {
+						budgetErr = err
+						log.Printf("ERROR: %v, aborting the run", err)
+						cancelRun()
+					})
+				}
+				errChan <- err
 			}
 		}()
 	}
 
-	for i := 0; i < len(s.Config.URLs); i++ {
+	for range urls {
 		<-doneChan
 	}
+	s.postCapture.wait()
+
+	s.Manifest.FinishedAt = time.Now()
+	if !s.writesFiles() {
+		log.Printf("Captures kept in memory, not writing the run manifest")
+	} else if path, err := s.Manifest.writeManifest(s.Config.OutputDir); err != nil {
+		log.Printf("ERROR: %v", err)
+	} else {
+		log.Printf("Wrote run manifest: %s", path)
+	}
+
+	// Watch mode compares images across runs, so it keeps the loose files
+	var zipErr error
+	if s.Config.ZipOutput && !s.watching {
+		_, zipErr = s.zipRun()
+	}
+
+	s.printSummary()
+	s.timings.report()
+
+	if budgetErr != nil {
+		return budgetErr
+	}
 
 	select {
 	case err := <-errChan:
 		return err
 	default:
-		return nil
+		return zipErr
 	}
 }
@@ -0,0 +1,130 @@
+package screenshot
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"screenshot-tool/config"
+)
+
+func TestViewportSections(t *testing.T) {
+	const viewportHeight = 800
+	tests := []struct {
+		pageHeight float64
+		scrollPos  []float64
+	}{
+		{1, []float64{0}},
+		{799, []float64{0}},
+		{800, []float64{0}},
+		{801, []float64{0, 1}},
+		{1600, []float64{0, 800}},
+		{2500, []float64{0, 800, 1600, 1700}},
+	}
+	for _, tt := range tests {
+		sections := viewportSections(tt.pageHeight, viewportHeight, false)
+		if len(sections) != len(tt.scrollPos) {
+			t.Errorf("page %.0f: %d sections, want %d", tt.pageHeight, len(sections), len(tt.scrollPos))
+			continue
+		}
+		for i, section := range sections {
+			if section.scrollPos != tt.scrollPos[i] {
+				t.Errorf("page %.0f: section %d at %.0f, want %.0f", tt.pageHeight, i+1, section.scrollPos, tt.scrollPos[i])
+			}
+		}
+	}
+
+	if sections := viewportSections(5000, viewportHeight, true); len(sections) != 1 {
+		t.Errorf("aboveFoldOnly: %d sections, want 1", len(sections))
+	}
+}
+
+// fakeTab is a page of known rows behind one shared scroll position, like a browser tab that
+// several section captures scroll at once
+type fakeTab struct {
+	mu             sync.Mutex
+	pageHeight     int
+	viewportHeight int
+	scale          int // Device pixels per CSS pixel
+	scrollY        int
+	rng            *rand.Rand
+}
+
+// rowColor encodes a page row in a pixel color, so tiles show which rows they hold
+func rowColor(row int) color.RGBA {
+	return color.RGBA{R: uint8(row), G: uint8(row >> 8), A: 255}
+}
+
+// capture scrolls to scrollPos, lets time pass as the real capture does, then screenshots
+// whatever the tab shows by then
+func (tab *fakeTab) capture(t *testing.T) func(i int, scrollPos float64) ([]byte, float64, error) {
+	return func(i int, scrollPos float64) ([]byte, float64, error) {
+		tab.mu.Lock()
+		tab.scrollY = min(int(scrollPos), tab.pageHeight-tab.viewportHeight)
+		delay := time.Duration(tab.rng.Intn(3)) * time.Millisecond
+		tab.mu.Unlock()
+
+		time.Sleep(delay)
+
+		tab.mu.Lock()
+		defer tab.mu.Unlock()
+		img := image.NewRGBA(image.Rect(0, 0, 2, tab.viewportHeight*tab.scale))
+		for y := 0; y < img.Bounds().Dy(); y++ {
+			c := rowColor(tab.scrollY + y/tab.scale)
+			img.SetRGBA(0, y, c)
+			img.SetRGBA(1, y, c)
+		}
+		buf, err := encodePNG(img, png.BestSpeed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf, float64(tab.scrollY), nil
+	}
+}
+
+func TestCaptureSectionsTilePage(t *testing.T) {
+	const viewportHeight = 100
+	s := &Screenshoter{Config: &config.Config{CropSectionOverlap: true}}
+
+	for _, pageHeight := range []int{100, 250, 300, 333, 999} {
+		for _, scale := range []int{1, 2, 3} {
+			tab := &fakeTab{pageHeight: pageHeight, viewportHeight: viewportHeight, scale: scale, rng: rand.New(rand.NewSource(int64(pageHeight)))}
+			sections := viewportSections(float64(pageHeight), viewportHeight, false)
+
+			var mu sync.Mutex
+			tiles := make([][]byte, len(sections))
+			write := func(i int, buf []byte) error {
+				mu.Lock()
+				defer mu.Unlock()
+				tiles[i] = buf
+				return nil
+			}
+			if err := s.captureSections("test", sections, viewportHeight, tab.capture(t), write); err != nil {
+				t.Fatalf("page %d at %dx: %v", pageHeight, scale, err)
+			}
+
+			// Stacked in order, the tiles must show every page row exactly once
+			want := 0
+			for i, tile := range tiles {
+				img, err := png.Decode(bytes.NewReader(tile))
+				if err != nil {
+					t.Fatalf("page %d at %dx: tile %d: %v", pageHeight, scale, i+1, err)
+				}
+				for y := 0; y < img.Bounds().Dy(); y++ {
+					if got := img.At(0, y); got != rowColor(want/scale) {
+						t.Fatalf("page %d at %dx: tile %d row %d shows %v, want page row %d", pageHeight, scale, i+1, y, got, want/scale)
+					}
+					want++
+				}
+			}
+			if want != pageHeight*scale {
+				t.Errorf("page %d at %dx: tiles are %dpx high, want %dpx", pageHeight, scale, want, pageHeight*scale)
+			}
+		}
+	}
+}
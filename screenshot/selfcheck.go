@@ -0,0 +1,90 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// selfCheckTimeout bounds the whole self-check, including starting Docker Chrome
+const selfCheckTimeout = 2 * time.Minute
+
+// selfCheckViewport is the window the self-check opens; only a 1x1 clip of it is captured
+var selfCheckViewport = config.Viewport{Width: 800, Height: 600}
+
+// SelfCheck verifies that screenshots can be taken with cfg's Chrome settings, without
+// touching any of its URLs: Chrome is found or started for cfg.ChromeMode, a tab loads
+// about:blank and a 1x1 screenshot of it is captured and decoded. The Chrome used and its
+// version are logged, and the returned error names the step that failed.
+func SelfCheck(ctx context.Context, cfg *config.Config) error {
+	ctx, cancel := context.WithTimeout(ctx, selfCheckTimeout)
+	defer cancel()
+
+	s := NewScreenshoter(cfg)
+	defer func() {
+		if err := s.Close(); err != nil {
+			log.Printf("Self-check cleanup failed: %v", err)
+		}
+	}()
+
+	log.Printf("Self-check: Chrome mode %s, headless %t", cfg.ChromeMode, cfg.Headless)
+	allocCtx, cancelAlloc, remote, err := s.newAllocator(ctx, selfCheckViewport)
+	if err != nil {
+		return fmt.Errorf("self-check failed to find or start Chrome: %w", err)
+	}
+	defer cancelAlloc()
+
+	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx, chromedp.WithLogf(log.Printf))
+	defer cancelBrowser()
+
+	var product, userAgent string
+	if err := chromedp.Run(browserCtx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		_, product, _, userAgent, _, err = browser.GetVersion().Do(ctx)
+		return err
+	})); err != nil {
+		return fmt.Errorf("self-check failed to start Chrome: %w", err)
+	}
+	if remote {
+		log.Printf("Self-check: connected to Docker Chrome %s", product)
+	} else if execPath, err := findChromeExecutable(); err == nil {
+		log.Printf("Self-check: launched local Chrome %s at %s", product, execPath)
+	} else {
+		log.Printf("Self-check: launched Chrome %s from chromedp's default location", product)
+	}
+	log.Printf("Self-check: user agent %s", userAgent)
+
+	if err := chromedp.Run(browserCtx, chromedp.Navigate("about:blank")); err != nil {
+		return fmt.Errorf("self-check failed to navigate to about:blank: %w", err)
+	}
+
+	var buf []byte
+	if err := chromedp.Run(browserCtx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		buf, err = page.CaptureScreenshot().
+			WithFormat(page.CaptureScreenshotFormatPng).
+			WithClip(&page.Viewport{X: 0, Y: 0, Width: 1, Height: 1, Scale: 1}).
+			Do(ctx)
+		return err
+	})); err != nil {
+		return fmt.Errorf("self-check failed to capture a screenshot: %w", err)
+	}
+
+	img, err := decodeImage(buf)
+	if err != nil {
+		return fmt.Errorf("self-check captured an invalid screenshot: %w", err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != 1 || bounds.Dy() != 1 {
+		return fmt.Errorf("self-check captured a %dx%d screenshot, expected 1x1", bounds.Dx(), bounds.Dy())
+	}
+
+	log.Printf("Self-check passed: captured a 1x1 screenshot of about:blank (%d bytes)", len(buf))
+	return nil
+}
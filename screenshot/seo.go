@@ -0,0 +1,173 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/cdproto/cdp"
+	cdpio "github.com/chromedp/cdproto/io"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// seoTagsScript reads the page's title, meta description and og: properties, and the URL of
+// its favicon: the first <link rel="icon">, or /favicon.ico of the page's origin
+const seoTagsScript = `
+(function() {
+	var description = document.querySelector('meta[name="description" i]');
+	var openGraph = {};
+	var metas = document.querySelectorAll('meta[property^="og:"]');
+	for (var i = 0; i < metas.length; i++) {
+		var property = metas[i].getAttribute('property');
+		if (!(property in openGraph)) {
+			openGraph[property] = metas[i].getAttribute('content') || '';
+		}
+	}
+	var icon = document.querySelector('link[rel~="icon" i][href]');
+	return {
+		url: location.href,
+		title: document.title,
+		description: description ? description.getAttribute('content') || '' : '',
+		openGraph: openGraph,
+		favicon: icon ? icon.href : new URL('/favicon.ico', location.href).href
+	};
+})()`
+
+// seoTags is written as <name>-seo.json by saveSEO
+type seoTags struct {
+	URL          string            `json:"url"` // Page location the tags were read from
+	Title        string            `json:"title"`
+	Description  string            `json:"description,omitempty"`
+	OpenGraph    map[string]string `json:"openGraph,omitempty"`    // og: properties by full name, e.g. "og:image"
+	Favicon      string            `json:"favicon"`                // URL the favicon was loaded from
+	FaviconFile  string            `json:"faviconFile,omitempty"`  // Where it was written
+	FaviconError string            `json:"faviconError,omitempty"` // Why it could not be loaded
+}
+
+// faviconExtensions maps favicon content types to file extensions
+var faviconExtensions = map[string]string{
+	"image/x-icon":             "ico",
+	"image/vnd.microsoft.icon": "ico",
+	"image/png":                "png",
+	"image/svg+xml":            "svg",
+	"image/gif":                "gif",
+	"image/jpeg":               "jpg",
+	"image/webp":               "webp",
+}
+
+// saveSEO writes the page's title, description and og: tags as <name>-seo.json, and its favicon
+// as <name>-favicon.<ext>. The favicon is loaded through the browser, with the tab's cookies and
+// proxy; when it cannot be, the reason is recorded in the JSON instead of failing the capture.
+func (s *Screenshoter) saveSEO(ctx context.Context, urlConfig config.URLConfig, viewportDir string) error {
+	var tags seoTags
+	if err := chromedp.Evaluate(seoTagsScript, &tags).Do(ctx); err != nil {
+		return fmt.Errorf("failed to read SEO tags: %w", err)
+	}
+
+	base := sanitizeFilename(urlConfig.Name)
+	data, contentType, err := loadResource(ctx, tags.Favicon)
+	if err == nil {
+		faviconPath := filepath.Join(viewportDir, fmt.Sprintf("%s-favicon.%s", base, faviconExtension(tags.Favicon, contentType)))
+		if err = s.Sink.Write(faviconPath, data); err == nil {
+			tags.FaviconFile = faviconPath
+		}
+	}
+	if err != nil {
+		log.Printf("Warning: failed to save favicon of %s from %s: %v", urlConfig.Name, tags.Favicon, err)
+		tags.FaviconError = err.Error()
+	}
+
+	out, err := json.MarshalIndent(tags, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode SEO tags: %w", err)
+	}
+	seoPath := filepath.Join(viewportDir, base+"-seo.json")
+	if err := s.Sink.Write(seoPath, out); err != nil {
+		return fmt.Errorf("failed to write SEO tags: %w", err)
+	}
+
+	log.Printf("Saved SEO tags for %s (%d og: tags): %s", urlConfig.Name, len(tags.OpenGraph), seoPath)
+	return nil
+}
+
+// loadResource fetches urlstr through the browser on behalf of the tab's main frame, returning
+// its body and content type
+func loadResource(ctx context.Context, urlstr string) ([]byte, string, error) {
+	frameTree, err := page.GetFrameTree().Do(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to find the main frame: %w", err)
+	}
+	resource, err := network.LoadNetworkResource(urlstr, &network.LoadNetworkResourceOptions{IncludeCredentials: true}).
+		WithFrameID(frameTree.Frame.ID).Do(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if !resource.Success {
+		if resource.NetErrorName != "" {
+			return nil, "", fmt.Errorf("request failed: %s", resource.NetErrorName)
+		}
+		return nil, "", fmt.Errorf("request failed with status %.0f", resource.HTTPStatusCode)
+	}
+	if resource.HTTPStatusCode >= 400 {
+		return nil, "", fmt.Errorf("request failed with status %.0f", resource.HTTPStatusCode)
+	}
+	if resource.Stream == "" {
+		return nil, "", fmt.Errorf("response with status %.0f has no body", resource.HTTPStatusCode)
+	}
+	defer cdpio.Close(resource.Stream).Do(ctx)
+
+	var contentType string
+	for name, value := range resource.Headers {
+		if strings.EqualFold(name, "Content-Type") {
+			contentType, _ = value.(string)
+		}
+	}
+
+	// IO.read reports whether each chunk is base64, which cdpio.ReadParams.Do drops
+	var body []byte
+	for {
+		var chunk cdpio.ReadReturns
+		if err := cdp.Execute(ctx, cdpio.CommandRead, cdpio.Read(resource.Stream), &chunk); err != nil {
+			return nil, "", fmt.Errorf("failed to read response: %w", err)
+		}
+		if chunk.Base64encoded {
+			decoded, err := base64.StdEncoding.DecodeString(chunk.Data)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to decode response: %w", err)
+			}
+			body = append(body, decoded...)
+		} else {
+			body = append(body, chunk.Data...)
+		}
+		if chunk.EOF {
+			return body, contentType, nil
+		}
+	}
+}
+
+// faviconExtension picks the file extension for a favicon from its content type, then from its
+// URL, defaulting to "ico"
+func faviconExtension(urlstr, contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if ext, ok := faviconExtensions[mediaType]; ok {
+			return ext
+		}
+	}
+	if u, err := url.Parse(urlstr); err == nil {
+		if ext := strings.ToLower(strings.TrimPrefix(path.Ext(u.Path), ".")); ext != "" && len(ext) <= 4 {
+			return sanitizeFilename(ext)
+		}
+	}
+	return "ico"
+}
@@ -0,0 +1,186 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/chromedp"
+)
+
+// maxRequestBytes bounds the JSON body accepted by the /screenshot endpoint
+const maxRequestBytes = 1 << 20
+
+// screenshotRequest is the JSON body accepted by the /screenshot endpoint
+type screenshotRequest struct {
+	URL      string           `json:"url"`
+	Viewport *config.Viewport `json:"viewport,omitempty"` // Default 1280x800
+	Format   string           `json:"format,omitempty"`   // "png" or "jpeg" (default: the config's fileFormat)
+	Quality  int              `json:"quality,omitempty"`  // JPEG quality (default: the config's quality)
+	Delay    *int             `json:"delay,omitempty"`    // Settle delay in milliseconds (default 1000)
+	FullPage *bool            `json:"fullPage,omitempty"` // Capture the full page (default true) or only the first viewport
+	Cookies  []config.Cookie  `json:"cookies,omitempty"`
+}
+
+// server answers screenshot requests from one warm browser, capturing each request in its
+// own browser context so cookies cannot leak between callers
+type server struct {
+	s          *Screenshoter
+	browserCtx context.Context
+	remote     bool // Docker Chrome, which gets no local-only options such as proxy authentication
+	sem        chan struct{}
+}
+
+// Serve starts an HTTP server on addr with a POST /screenshot endpoint returning image bytes and
+// a GET /healthz endpoint. Chrome is started once up front and kept warm; at most Concurrency
+// captures run at a time. Serve returns when ctx is cancelled or the server fails.
+func (s *Screenshoter) Serve(ctx context.Context, addr string) error {
+	allocCtx, cancelAlloc, remote, err := s.newAllocator(ctx, config.Viewport{Width: 1280, Height: 800})
+	if err != nil {
+		return err
+	}
+	defer cancelAlloc()
+
+	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx, chromedp.WithLogf(log.Printf))
+	defer cancelBrowser()
+	if err := chromedp.Run(browserCtx); err != nil {
+		return fmt.Errorf("failed to start browser: %w", err)
+	}
+
+	srv := &server{
+		s:          s,
+		browserCtx: browserCtx,
+		remote:     remote,
+		sem:        make(chan struct{}, s.Config.Concurrency),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/screenshot", srv.handleScreenshot)
+	mux.HandleFunc("/healthz", srv.handleHealthz)
+	httpServer := &http.Server{Addr: addr, Handler: mux}
+
+	errChan := make(chan error, 1)
+	go func() {
+		log.Printf("Serving screenshots on %s (concurrency %d)", addr, s.Config.Concurrency)
+		errChan <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errChan:
+		return fmt.Errorf("HTTP server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down HTTP server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to shut down HTTP server: %w", err)
+	}
+	return nil
+}
+
+// handleHealthz reports whether the warm browser is still running
+func (srv *server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if err := srv.browserCtx.Err(); err != nil {
+		http.Error(w, "browser unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+// checkRequestURL rejects request URLs that are not absolute http or https URLs, so callers
+// cannot have Chrome render local files or browser internals such as file:// or chrome://
+func checkRequestURL(rawURL string) error {
+	if rawURL == "" {
+		return errors.New("url is required")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported url scheme %q (supported: http, https)", u.Scheme)
+	}
+	if u.Host == "" {
+		return errors.New("url must include a host")
+	}
+	return nil
+}
+
+// handleScreenshot captures the URL in the request body and writes the image back
+func (srv *server) handleScreenshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req screenshotRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := checkRequestURL(req.URL); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts := []Option{WithFormat(req.Format), WithQuality(req.Quality), WithCookies(req.Cookies...)}
+	if req.Viewport != nil {
+		opts = append(opts, WithViewport(req.Viewport.Width, req.Viewport.Height))
+	}
+	if req.Delay != nil {
+		opts = append(opts, WithDelay(*req.Delay))
+	}
+	if req.FullPage != nil {
+		opts = append(opts, WithFullPage(*req.FullPage))
+	}
+	options, err := srv.s.singleOptions(opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case srv.sem <- struct{}{}:
+		defer func() { <-srv.sem }()
+	case <-r.Context().Done():
+		return
+	}
+
+	// A fresh browser context per request, cancelled with the request or the server
+	tabCtx, cancelTab := chromedp.NewContext(srv.browserCtx, chromedp.WithNewBrowserContext())
+	defer cancelTab()
+	stop := context.AfterFunc(r.Context(), cancelTab)
+	defer stop()
+	if !srv.remote && srv.s.Config.Proxy != "" {
+		if err := srv.s.enableProxyAuth(tabCtx); err != nil {
+			log.Printf("ERROR: failed to set up proxy authentication: %v", err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+
+	start := time.Now()
+	data, err := srv.s.captureSingle(tabCtx, req.URL, options)
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	log.Printf("Served screenshot of %s (%d bytes) in %v", req.URL, len(data), time.Since(start))
+
+	w.Header().Set("Content-Type", http.DetectContentType(data))
+	w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+	if _, err := w.Write(data); err != nil {
+		log.Printf("Warning: failed to write response for %s: %v", req.URL, err)
+	}
+}
@@ -0,0 +1,27 @@
+package screenshot
+
+import "testing"
+
+func TestCheckRequestURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://example.com/page", false},
+		{"http://localhost:8080", false},
+		{"", true},
+		{"file:///etc/passwd", true},
+		{"chrome://settings", true},
+		{"view-source:https://example.com", true},
+		{"javascript:alert(1)", true},
+		{"example.com", true},
+		{"http://", true},
+		{"http://[::1", true},
+	}
+	for _, tt := range tests {
+		err := checkRequestURL(tt.url)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkRequestURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
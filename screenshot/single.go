@@ -0,0 +1,237 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+)
+
+// Option configures a CaptureSingle call
+type Option func(*singleOptions)
+
+type singleOptions struct {
+	viewport config.Viewport
+	format   string
+	quality  int
+	delay    int
+	fullPage bool
+	cookies  []config.Cookie
+}
+
+// WithViewport sets the viewport size (default 1280x800)
+func WithViewport(width, height int) Option {
+	return func(o *singleOptions) {
+		o.viewport = config.Viewport{Width: width, Height: height}
+	}
+}
+
+// WithFormat sets the image format, "png" or "jpeg" (default: the Screenshoter's FileFormat)
+func WithFormat(format string) Option {
+	return func(o *singleOptions) {
+		o.format = format
+	}
+}
+
+// WithQuality sets the JPEG quality (default: the Screenshoter's Quality)
+func WithQuality(quality int) Option {
+	return func(o *singleOptions) {
+		o.quality = quality
+	}
+}
+
+// WithDelay sets how long to let the page settle after loading, in milliseconds (default 1000)
+func WithDelay(ms int) Option {
+	return func(o *singleOptions) {
+		o.delay = ms
+	}
+}
+
+// WithFullPage selects a full page capture (the default) or only the first viewport
+func WithFullPage(fullPage bool) Option {
+	return func(o *singleOptions) {
+		o.fullPage = fullPage
+	}
+}
+
+// WithCookies sets cookies before loading the page. A cookie without a domain gets the URL's host.
+func WithCookies(cookies ...config.Cookie) Option {
+	return func(o *singleOptions) {
+		o.cookies = append(o.cookies, cookies...)
+	}
+}
+
+// CaptureSingle captures one screenshot of url and returns the image bytes without writing
+// anything to disk or the manifest. Chrome selection, waiting and image processing follow the
+// Screenshoter's Config, overridden by opts.
+func (s *Screenshoter) CaptureSingle(ctx context.Context, url string, opts ...Option) ([]byte, error) {
+	options, err := s.singleOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	allocCtx, cancelAlloc, remote, err := s.newAllocator(ctx, options.viewport)
+	if err != nil {
+		return nil, err
+	}
+	defer cancelAlloc()
+
+	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx, chromedp.WithLogf(log.Printf))
+	defer cancelBrowser()
+	if !remote && s.Config.Proxy != "" {
+		if err := s.enableProxyAuth(browserCtx); err != nil {
+			return nil, fmt.Errorf("failed to set up proxy authentication: %w", err)
+		}
+	}
+
+	return s.captureSingle(browserCtx, url, options)
+}
+
+// singleOptions applies opts over the Screenshoter's defaults and validates the result
+func (s *Screenshoter) singleOptions(opts []Option) (singleOptions, error) {
+	options := singleOptions{
+		viewport: config.Viewport{Width: 1280, Height: 800},
+		format:   s.Config.FileFormat,
+		quality:  s.Config.Quality,
+		delay:    1000,
+		fullPage: true,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.format == "" {
+		options.format = "png"
+	}
+	if options.format != "png" && options.format != "jpeg" {
+		return options, fmt.Errorf("unsupported format: %s (supported: png, jpeg)", options.format)
+	}
+	if options.format == "jpeg" && options.quality == 0 {
+		options.quality = 90
+	}
+	if options.quality < 0 || options.quality > 100 {
+		return options, fmt.Errorf("quality must be between 1 and 100")
+	}
+	if options.viewport.Width < config.MinViewportSize || options.viewport.Width > config.MaxViewportSize ||
+		options.viewport.Height < config.MinViewportSize || options.viewport.Height > config.MaxViewportSize {
+		return options, fmt.Errorf("invalid viewport %dx%d, width and height must be between %d and %d",
+			options.viewport.Width, options.viewport.Height, config.MinViewportSize, config.MaxViewportSize)
+	}
+	if options.delay < 0 {
+		return options, fmt.Errorf("delay must not be negative")
+	}
+	return options, nil
+}
+
+// captureSingle captures url in the tab of tabCtx, which is created on first use
+func (s *Screenshoter) captureSingle(tabCtx context.Context, url string, options singleOptions) ([]byte, error) {
+	// Work on a copy so the options do not leak into the Screenshoter's own runs
+	cfg := *s.Config
+	cfg.FileFormat = options.format
+	cfg.Quality = options.quality
+	single := &Screenshoter{Config: &cfg, Manifest: &Manifest{StartedAt: time.Now()}}
+
+	urlConfig := config.URLConfig{
+		Name:         extractDomainFromURL(url),
+		URL:          url,
+		Viewports:    []config.Viewport{options.viewport},
+		Delay:        options.delay,
+		WaitStrategy: cfg.WaitStrategy,
+	}
+	viewport := options.viewport
+
+	var buf []byte
+	tasks := []chromedp.Action{
+		emulation.SetDeviceMetricsOverride(int64(viewport.Width), int64(viewport.Height), 1, false),
+	}
+	for _, cookie := range options.cookies {
+		tasks = append(tasks, setCookie(urlConfig, cookie))
+	}
+	tasks = append(tasks,
+		single.navigateTo(url),
+		single.waitForPage(urlConfig),
+	)
+	tasks = append(tasks, single.waitBeforeCapture(urlConfig, viewport)...)
+	tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
+		if !options.fullPage {
+			return chromedp.CaptureScreenshot(&buf).Do(ctx)
+		}
+		var height float64
+		if err := chromedp.Evaluate(`Math.max(document.body.scrollHeight, document.documentElement.scrollHeight)`, &height).Do(ctx); err != nil {
+			return err
+		}
+		return single.captureFullHeight(ctx, urlConfig, int64(viewport.Width), int64(height), &buf)
+	}))
+
+	if err := chromedp.Run(tabCtx, single.slowMo(tasks)...); err != nil {
+		return nil, fmt.Errorf("failed to capture %s: %w", url, err)
+	}
+
+	processed, err := single.processImage(urlConfig.Name+"."+options.format, buf, options.format, cfg.Quality, nil)
+	if err != nil {
+		return nil, err
+	}
+	return processed.data, nil
+}
+
+// setCookie sets cookie before the first navigation, defaulting its domain to the URL's host
+// and its path to the root
+func setCookie(urlConfig config.URLConfig, cookie config.Cookie) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		param := cookieParam(cookie, urlConfig.URL, nil)
+		var err error
+		if cookie.ThirdParty {
+			err = setThirdPartyCookie(ctx, param)
+		} else {
+			err = network.SetCookie(param.Name, param.Value).
+				WithDomain(param.Domain).
+				WithPath(param.Path).
+				WithHTTPOnly(param.HTTPOnly).
+				WithSecure(param.Secure).
+				Do(ctx)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to set cookie %s: %w", cookie.Name, err)
+		}
+		return nil
+	})
+}
+
+// cookieParam returns cookie as set for a page at pageURL: without a domain it gets the page's
+// host, without a path the root. A nil expires makes it a session cookie.
+func cookieParam(cookie config.Cookie, pageURL string, expires *cdp.TimeSinceEpoch) *network.CookieParam {
+	domain := cookie.Domain
+	if domain == "" {
+		domain = extractDomainFromURL(pageURL)
+	}
+	path := cookie.Path
+	if path == "" {
+		path = "/"
+	}
+	return &network.CookieParam{
+		Name:     cookie.Name,
+		Value:    cookie.Value,
+		Domain:   domain,
+		Path:     path,
+		Secure:   cookie.Secure,
+		HTTPOnly: cookie.HTTPOnly,
+		Expires:  expires,
+	}
+}
+
+// setThirdPartyCookie sets param for its own domain through Storage.setCookies, which writes
+// to the browser context's cookie jar directly instead of going through the current page, so
+// third-party and SSO cookies can be seeded before their domain is ever visited.
+func setThirdPartyCookie(ctx context.Context, param *network.CookieParam) error {
+	return storage.SetCookies([]*network.CookieParam{param}).
+		WithBrowserContextID(browserContextID(ctx)).
+		Do(ctx)
+}
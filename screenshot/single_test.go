@@ -0,0 +1,105 @@
+package screenshot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+)
+
+func TestCookieParam(t *testing.T) {
+	expires := cdp.TimeSinceEpoch{}
+	tests := []struct {
+		name       string
+		cookie     config.Cookie
+		pageURL    string
+		wantDomain string
+		wantPath   string
+	}{
+		{"defaults", config.Cookie{Name: "a", Value: "1"}, "https://www.example.com/page?x=1", "example.com", "/"},
+		{"port", config.Cookie{Name: "a", Value: "1"}, "http://localhost:8080/", "localhost", "/"},
+		{"explicit", config.Cookie{Name: "a", Value: "1", Domain: ".shop.example.com", Path: "/cart"}, "https://example.com/", ".shop.example.com", "/cart"},
+		{"third party", config.Cookie{Name: "sso", Value: "t", Domain: "auth.example.net", ThirdParty: true, Secure: true, HTTPOnly: true}, "https://example.com/", "auth.example.net", "/"},
+	}
+	for _, tt := range tests {
+		for _, exp := range []*cdp.TimeSinceEpoch{nil, &expires} {
+			param := cookieParam(tt.cookie, tt.pageURL, exp)
+			if param.Domain != tt.wantDomain || param.Path != tt.wantPath {
+				t.Errorf("%s: domain %q path %q, want %q %q", tt.name, param.Domain, param.Path, tt.wantDomain, tt.wantPath)
+			}
+			if param.Name != tt.cookie.Name || param.Value != tt.cookie.Value ||
+				param.Secure != tt.cookie.Secure || param.HTTPOnly != tt.cookie.HTTPOnly {
+				t.Errorf("%s: %+v does not carry the cookie's fields %+v", tt.name, param, tt.cookie)
+			}
+			if param.Expires != exp {
+				t.Errorf("%s: expires %v, want %v", tt.name, param.Expires, exp)
+			}
+		}
+	}
+}
+
+func TestSetThirdPartyCookie(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts Chrome")
+	}
+	chromePath, err := findChromeExecutable()
+	if err != nil {
+		t.Skipf("Chrome is not available: %v", err)
+	}
+
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case received <- r.Header.Get("Cookie"):
+		default:
+		}
+		w.Write([]byte("<html><body>sso</body></html>"))
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sso.test stands in for an identity provider on another domain than the captured page
+	options := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.ExecPath(chromePath),
+		chromedp.NoSandbox,
+		chromedp.Flag("host-resolver-rules", "MAP sso.test 127.0.0.1"),
+	)
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), options...)
+	defer cancelAlloc()
+	ctx, cancel := chromedp.NewContext(allocCtx)
+	defer cancel()
+	ctx, cancelTimeout := context.WithTimeout(ctx, 30*time.Second)
+	defer cancelTimeout()
+
+	urlConfig := config.URLConfig{Name: "page", URL: "https://example.com/"}
+	cookie := config.Cookie{Name: "sso", Value: "token", Domain: "sso.test", ThirdParty: true}
+	var documentCookie string
+	err = chromedp.Run(ctx,
+		setCookie(urlConfig, cookie),
+		chromedp.Navigate("http://sso.test:"+serverURL.Port()+"/"),
+		chromedp.Evaluate("document.cookie", &documentCookie),
+	)
+	if err != nil {
+		t.Fatalf("navigating with a third-party cookie: %v", err)
+	}
+
+	var requestCookie string
+	select {
+	case requestCookie = <-received:
+	default:
+	}
+	if !strings.Contains(requestCookie, "sso=token") && !strings.Contains(documentCookie, "sso=token") {
+		t.Errorf("cookie for sso.test missing after navigation: request %q, document.cookie %q", requestCookie, documentCookie)
+	}
+}
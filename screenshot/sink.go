@@ -0,0 +1,127 @@
+package screenshot
+
+import (
+	"os"
+	"sync"
+
+	"screenshot-tool/config"
+)
+
+// Sink receives the images and artifacts produced by a capture. path is where the file
+// would live under OutputDir; sinks that do not write to disk use it only as a name.
+type Sink interface {
+	Write(path string, data []byte) error
+}
+
+// FileSink writes captures to disk. It is the default sink.
+type FileSink struct{}
+
+// Write writes data to path
+func (FileSink) Write(path string, data []byte) error {
+	return os.WriteFile(path, data, 0644)
+}
+
+// MemoryFile is a capture collected by a MemorySink
+type MemoryFile struct {
+	Path string
+	Data []byte
+}
+
+// MemorySink collects captures in memory without touching the filesystem
+type MemorySink struct {
+	mu    sync.Mutex
+	files []MemoryFile
+}
+
+// Write stores data under path
+func (m *MemorySink) Write(path string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files = append(m.files, MemoryFile{Path: path, Data: data})
+	return nil
+}
+
+// Files returns everything collected so far, in write order
+func (m *MemorySink) Files() []MemoryFile {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MemoryFile(nil), m.files...)
+}
+
+// Images returns the data of everything collected so far, in write order
+func (m *MemorySink) Images() [][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	images := make([][]byte, len(m.files))
+	for i, file := range m.files {
+		images[i] = file.Data
+	}
+	return images
+}
+
+// read returns the capture stored under path
+func (m *MemorySink) read(path string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, file := range m.files {
+		if file.Path == path {
+			return file.Data, true
+		}
+	}
+	return nil, false
+}
+
+// remove discards the capture stored under path
+func (m *MemorySink) remove(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.files[:0]
+	for _, file := range m.files {
+		if file.Path != path {
+			kept = append(kept, file)
+		}
+	}
+	m.files = kept
+}
+
+// Reset discards everything collected so far
+func (m *MemorySink) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files = nil
+}
+
+// newSink returns the sink selected by Config.Output
+func newSink(cfg *config.Config) Sink {
+	if cfg.Output == config.OutputMemory {
+		return &MemorySink{}
+	}
+	return FileSink{}
+}
+
+// writesFiles reports whether captures go to disk, in which case directories, cookie logs
+// and the manifest are written alongside them
+func (s *Screenshoter) writesFiles() bool {
+	_, ok := s.Sink.(FileSink)
+	return ok
+}
+
+// readCapture returns a capture written through the sink
+func (s *Screenshoter) readCapture(path string) ([]byte, error) {
+	if memory, ok := s.Sink.(*MemorySink); ok {
+		if data, ok := memory.read(path); ok {
+			return data, nil
+		}
+		return nil, os.ErrNotExist
+	}
+	return os.ReadFile(path)
+}
+
+// removeCapture deletes a capture written through the sink, so it can be taken again
+func (s *Screenshoter) removeCapture(path string) error {
+	if memory, ok := s.Sink.(*MemorySink); ok {
+		memory.remove(path)
+		return nil
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,113 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"log"
+	"time"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+)
+
+// Height limits for a single Chrome screenshot
+const (
+	maxCaptureHeight      = 16384
+	fallbackCaptureHeight = 8192
+	stitchChunkHeight     = 4096
+)
+
+// captureFullHeight captures the page at the given size into buf, applying Config.OnTallPage
+// when the page is taller than a single screenshot can be or when Chrome fails to capture it
+func (s *Screenshoter) captureFullHeight(ctx context.Context, urlConfig config.URLConfig, width, height int64, buf *[]byte) error {
+	if height > maxCaptureHeight {
+		switch s.Config.OnTallPage {
+		case config.TallPageError:
+			return fmt.Errorf("page height (%d) of %s exceeds maximum capture height (%d)", height, urlConfig.Name, maxCaptureHeight)
+		case config.TallPageStitch:
+			log.Printf("Page height (%d) exceeds maximum capture height (%d), stitching", height, maxCaptureHeight)
+			return s.captureStitched(ctx, width, height, buf)
+		default:
+			log.Printf("Warning: Page height (%d) exceeds maximum allowed height (%d). Limiting height.",
+				height, maxCaptureHeight)
+			height = maxCaptureHeight
+		}
+	}
+
+	if err := emulation.SetDeviceMetricsOverride(width, height, 1, false).Do(ctx); err != nil {
+		return err
+	}
+
+	err := chromedp.CaptureScreenshot(buf).Do(ctx)
+	if err == nil || height <= fallbackCaptureHeight {
+		return err
+	}
+
+	switch s.Config.OnTallPage {
+	case config.TallPageError:
+		return fmt.Errorf("screenshot of %s at height %d failed: %w", urlConfig.Name, height, err)
+	case config.TallPageStitch:
+		log.Printf("Screenshot capture failed, stitching instead...")
+		return s.captureStitched(ctx, width, height, buf)
+	default:
+		// Try with smaller height if capture failed
+		log.Printf("Screenshot capture failed, trying with reduced height...")
+		if err := emulation.SetDeviceMetricsOverride(width, fallbackCaptureHeight, 1, false).Do(ctx); err != nil {
+			return err
+		}
+		return chromedp.CaptureScreenshot(buf).Do(ctx)
+	}
+}
+
+// captureStitched captures the page in chunks of stitchChunkHeight by scrolling the window and
+// draws them onto a single PNG. Fixed elements such as sticky headers appear in every chunk.
+func (s *Screenshoter) captureStitched(ctx context.Context, width, height int64, buf *[]byte) error {
+	if err := emulation.SetDeviceMetricsOverride(width, stitchChunkHeight, 1, false).Do(ctx); err != nil {
+		return err
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+	for top := 0; top < int(height); top += stitchChunkHeight {
+		// The window cannot scroll past the end, so the last chunk may start above top
+		var scrollY float64
+		if err := chromedp.Evaluate(fmt.Sprintf(`window.scrollTo({top: %d, left: 0, behavior: 'instant'}); window.scrollY`, top), &scrollY).Do(ctx); err != nil {
+			return err
+		}
+		if err := chromedp.Sleep(200 * time.Millisecond).Do(ctx); err != nil {
+			return err
+		}
+
+		var chunk []byte
+		if err := chromedp.CaptureScreenshot(&chunk).Do(ctx); err != nil {
+			return fmt.Errorf("failed to capture chunk at %d: %w", top, err)
+		}
+		img, err := decodeImage(chunk)
+		if err != nil {
+			return err
+		}
+
+		bottom := top + stitchChunkHeight
+		if bottom > int(height) {
+			bottom = int(height)
+		}
+		draw.Draw(canvas, image.Rect(0, top, int(width), bottom), img, image.Pt(0, top-int(scrollY)), draw.Src)
+	}
+
+	if err := chromedp.Evaluate(`window.scrollTo(0, 0)`, nil).Do(ctx); err != nil {
+		return err
+	}
+
+	stitched, err := encodePNG(canvas, png.DefaultCompression)
+	if err != nil {
+		return err
+	}
+	*buf = stitched
+
+	log.Printf("Stitched %dx%d screenshot from %d chunks", width, height, (int(height)+stitchChunkHeight-1)/stitchChunkHeight)
+	return nil
+}
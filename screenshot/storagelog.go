@@ -0,0 +1,163 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/chromedp"
+)
+
+// readStorageScript returns the entries of localStorage and sessionStorage. Either may throw on
+// sandboxed or opaque-origin pages, which is reported per storage instead of failing.
+const readStorageScript = `
+(function() {
+	function read(name) {
+		try {
+			return {entries: Object.entries(window[name])};
+		} catch (e) {
+			return {error: String(e)};
+		}
+	}
+	return {localStorage: read('localStorage'), sessionStorage: read('sessionStorage')};
+})()`
+
+// storageArea is the content of one Web Storage area, or why it could not be read
+type storageArea struct {
+	Name    string      `json:"-"` // "localStorage" or "sessionStorage"
+	Entries [][2]string `json:"entries"`
+	Error   string      `json:"error"`
+}
+
+// pageStorage holds both Web Storage areas of the current page
+type pageStorage struct {
+	LocalStorage   storageArea `json:"localStorage"`
+	SessionStorage storageArea `json:"sessionStorage"`
+}
+
+// areas lists both storage areas, named, in log order
+func (p pageStorage) areas() []storageArea {
+	p.LocalStorage.Name = "localStorage"
+	p.SessionStorage.Name = "sessionStorage"
+	return []storageArea{p.LocalStorage, p.SessionStorage}
+}
+
+// readStorage reads localStorage and sessionStorage of the page loaded in the tab
+func readStorage(ctx context.Context) (pageStorage, error) {
+	var storage pageStorage
+	if err := chromedp.Evaluate(readStorageScript, &storage).Do(ctx); err != nil {
+		return storage, fmt.Errorf("failed to read storage: %w", err)
+	}
+	return storage, nil
+}
+
+// saveStorageLogs appends the page's localStorage and sessionStorage to <name>-storage.log and
+// <name>-storage.csv, next to the cookie logs. A page whose storage cannot be read is logged and
+// recorded as such rather than failing the capture.
+func saveStorageLogs(ctx context.Context, urlConfig config.URLConfig, stage string, urlDir string, viewport config.Viewport, screenshotType, timestamp string) {
+	storage, err := readStorage(ctx)
+	if err != nil {
+		log.Printf("Warning: %v for %s", err, urlConfig.Name)
+		return
+	}
+	for _, area := range storage.areas() {
+		if area.Error != "" {
+			log.Printf("Warning: %s is not accessible on %s: %s", area.Name, urlConfig.Name, area.Error)
+		}
+	}
+
+	if err := saveStorageTextLog(storage, urlConfig, stage, urlDir, viewport, screenshotType, timestamp); err != nil {
+		log.Printf("ERROR: Failed to save storage text log: %v", err)
+	}
+	if err := saveStorageCSV(storage, urlConfig, stage, urlDir, viewport, screenshotType, timestamp); err != nil {
+		log.Printf("ERROR: Failed to save storage CSV: %v", err)
+	}
+	log.Printf("Saved %d localStorage and %d sessionStorage entries to log files (stage: %s)",
+		len(storage.LocalStorage.Entries), len(storage.SessionStorage.Entries), stage)
+}
+
+// saveStorageTextLog appends a readable section for the stage to <name>-storage.log
+func saveStorageTextLog(storage pageStorage, urlConfig config.URLConfig, stage string, urlDir string, viewport config.Viewport, screenshotType, timestamp string) error {
+	path := filepath.Join(urlDir, fmt.Sprintf("%s-storage.log", sanitizeFilename(urlConfig.Name)))
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("\n\n========== %s ==========\n", stage))
+	text.WriteString(fmt.Sprintf("URL: %s (%s)\n", urlConfig.Name, urlConfig.URL))
+	text.WriteString(fmt.Sprintf("Timestamp: %s\n", timestamp))
+	text.WriteString(fmt.Sprintf("Viewport: %dx%d\n", viewport.Width, viewport.Height))
+	text.WriteString(fmt.Sprintf("Screenshot Type: %s\n", screenshotType))
+	text.WriteString(fmt.Sprintf("Step: %s\n", stage))
+
+	for _, area := range storage.areas() {
+		text.WriteString("\n----------------------------------------\n")
+		if area.Error != "" {
+			text.WriteString(fmt.Sprintf("%s not accessible: %s\n", area.Name, area.Error))
+			continue
+		}
+		text.WriteString(fmt.Sprintf("%s (%d):\n", area.Name, len(area.Entries)))
+		for _, entry := range area.Entries {
+			text.WriteString(fmt.Sprintf("  %s: %s\n", entry[0], entry[1]))
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.WriteString(text.String())
+	return err
+}
+
+// saveStorageCSV appends one row per storage entry to <name>-storage.csv, writing the header
+// when the file is new. Commas are escaped with a backslash as in the cookie CSV, and newlines
+// in values as \n.
+func saveStorageCSV(storage pageStorage, urlConfig config.URLConfig, stage string, urlDir string, viewport config.Viewport, screenshotType, timestamp string) error {
+	path := filepath.Join(urlDir, fmt.Sprintf("%s-storage.csv", sanitizeFilename(urlConfig.Name)))
+
+	writeHeader := false
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		writeHeader = true
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if writeHeader {
+		if _, err := file.WriteString("Timestamp,URL,URL_Name,Stage,Screenshot_Type,Viewport,Storage,Key,Value\n"); err != nil {
+			return err
+		}
+	}
+
+	escape := func(value string) string {
+		value = strings.ReplaceAll(value, ",", "\\,")
+		value = strings.ReplaceAll(value, "\r", "\\r")
+		return strings.ReplaceAll(value, "\n", "\\n")
+	}
+	for _, area := range storage.areas() {
+		for _, entry := range area.Entries {
+			line := fmt.Sprintf("%s,%s,%s,%s,%s,%dx%d,%s,%s,%s\n",
+				timestamp,
+				escape(urlConfig.URL),
+				escape(urlConfig.Name),
+				stage,
+				screenshotType,
+				viewport.Width, viewport.Height,
+				area.Name,
+				escape(entry[0]),
+				escape(entry[1]))
+			if _, err := file.WriteString(line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+)
+
+// captureSweep takes the full page captures of urlConfig with Config.ResponsiveSweep, for the
+// viewports in viewportDirs (viewport name to directory), and reports which viewports it
+// captured. Viewports it leaves out, and all of them if the sweep fails, are captured the usual
+// way in a page load of their own.
+func (s *Screenshoter) captureSweep(ctx context.Context, urlConfig config.URLConfig, viewportDirs map[string]string) map[string]bool {
+	if !s.Config.ResponsiveSweep || s.Config.AuditOnly ||
+		s.Config.CaptureMode == config.CaptureModeSections || s.aboveFoldOnly(urlConfig) {
+		return nil
+	}
+	if urlConfig.ForceMediaMatch {
+		log.Printf("Not sweeping %s: forceMediaMatch answers matchMedia for a single viewport per page load", urlConfig.Name)
+		return nil
+	}
+	if s.Config.SkipUnchanged {
+		log.Printf("Not sweeping %s: skipUnchanged checks each viewport in a page load of its own", urlConfig.Name)
+		return nil
+	}
+	if s.Config.SuspectRetries > 0 {
+		log.Printf("Not sweeping %s: suspectRetries captures each viewport again in a page load of its own", urlConfig.Name)
+		return nil
+	}
+
+	var viewports []config.Viewport
+	for _, viewport := range urlConfig.Viewports {
+		if _, ok := viewportDirs[viewport.String()]; !ok || !inViewports(urlConfig.FullPageViewports, viewport) {
+			continue
+		}
+		// Zoom is installed before the page loads, so a zoomed viewport needs a load of its own
+		if viewport.Zoom != 0 && viewport.Zoom != 1 {
+			log.Printf("Not sweeping %s at viewport %s: zoom needs a page load of its own", urlConfig.Name, viewport)
+			continue
+		}
+		viewports = append(viewports, viewport)
+	}
+	if len(viewports) < 2 {
+		return nil
+	}
+
+	start := time.Now()
+	for _, viewport := range viewports {
+		s.emitCapture(EventCaptureStarted, urlConfig, viewport, viewportDirs[viewport.String()], start, nil)
+	}
+	if err := s.sweep(ctx, urlConfig, viewports, viewportDirs); err != nil {
+		log.Printf("Warning: responsive sweep of %s failed, capturing each viewport separately: %v", urlConfig.Name, err)
+		for _, viewport := range viewports {
+			viewportDir := viewportDirs[viewport.String()]
+			s.Manifest.removeFiles(viewportDir, s.removeCapture)
+			s.emitCapture(EventCaptureFailed, urlConfig, viewport, viewportDir, start, err)
+		}
+		return nil
+	}
+
+	swept := make(map[string]bool)
+	for _, viewport := range viewports {
+		swept[viewport.String()] = true
+	}
+	return swept
+}
+
+// sweep loads urlConfig once at the first of viewports and captures the full page at each of
+// them by only changing the device metrics. What lazy content loads can depend on the width, so
+// the page settles and is scrolled through again after every resize. The HAR of the single page
+// load goes with the first viewport's captures; the other artifacts are saved for every viewport.
+// Errors from a Docker Chrome tab are wrapped in remoteCaptureError.
+func (s *Screenshoter) sweep(ctx context.Context, urlConfig config.URLConfig, viewports []config.Viewport, viewportDirs map[string]string) (err error) {
+	start := time.Now()
+	first := viewports[0]
+	firstDir := viewportDirs[first.String()]
+
+	browserCtx, closeTab, remote, err := s.openTab(ctx, first)
+	if err != nil {
+		return err
+	}
+	defer closeTab()
+	if remote {
+		defer func() {
+			if err != nil {
+				err = &remoteCaptureError{err: err}
+			}
+		}()
+	}
+
+	browserCtx, cancelCrash := watchForCrash(browserCtx, urlConfig, first)
+	defer cancelCrash()
+	defer func() { err = crashError(browserCtx, err) }()
+
+	if remote {
+		for _, option := range s.localOnlyOptions() {
+			log.Printf("Warning: %s only applies to local Chrome, ignoring it for Docker Chrome", option)
+		}
+	} else if s.Config.Proxy != "" {
+		if err := s.enableProxyAuth(browserCtx); err != nil {
+			return fmt.Errorf("failed to set up proxy authentication: %w", err)
+		}
+	}
+
+	s.handleDialogs(browserCtx, urlConfig)
+
+	var har *harRecorder
+	if s.Config.CaptureHAR {
+		if har, err = startHAR(browserCtx); err != nil {
+			return fmt.Errorf("failed to start HAR recording for %s: %w", urlConfig.Name, err)
+		}
+	}
+
+	if s.Config.RecordRedirects {
+		if err := s.recordRedirects(browserCtx, urlConfig); err != nil {
+			return fmt.Errorf("failed to start redirect recording for %s: %w", urlConfig.Name, err)
+		}
+	}
+
+	resetTab, err := s.prepareTab(browserCtx, urlConfig, first)
+	defer resetTab()
+	if err != nil {
+		return err
+	}
+
+	if urlConfig.ClearCookies || urlConfig.ClearStorage {
+		if err := chromedp.Run(browserCtx, s.clearBrowserState(urlConfig)); err != nil {
+			return fmt.Errorf("failed to clear browser state for %s: %w", urlConfig.Name, err)
+		}
+	}
+
+	if urlConfig.Login != nil {
+		if err := s.performLogin(browserCtx, urlConfig); err != nil {
+			return fmt.Errorf("login failed for %s: %w", urlConfig.Name, err)
+		}
+	}
+
+	if urlConfig.Warmup {
+		s.warmup(browserCtx, urlConfig)
+	}
+
+	log.Printf("Sweeping %s across %d viewports from one page load", urlConfig.Name, len(viewports))
+	tasks := s.timed(urlConfig, first, phaseNavigation,
+		emulation.SetDeviceMetricsOverride(int64(first.Width), int64(first.Height), 1, false),
+		s.navigate(urlConfig, first, firstDir, "full"))
+	storageTasks := []chromedp.Action{s.saveCookies(browserCtx, urlConfig, "before", firstDir, first, "full page")}
+	if len(urlConfig.Cookies) > 0 || len(urlConfig.LocalStorage) > 0 {
+		storageTasks = append(storageTasks,
+			s.setCookiesAndLocalStorage(browserCtx, urlConfig, first, firstDir, "after", "full page"),
+			s.reload(),
+			chromedp.Sleep(1*time.Second),
+		)
+	}
+	tasks = append(tasks, s.timed(urlConfig, first, phaseStorage, storageTasks...)...)
+	if err := chromedp.Run(browserCtx, s.slowMo(tasks)...); err != nil {
+		return fmt.Errorf("failed to load %s: %w", urlConfig.Name, err)
+	}
+
+	for i, viewport := range viewports {
+		viewportDir := viewportDirs[viewport.String()]
+		var buf []byte
+
+		var tasks []chromedp.Action
+		if i > 0 {
+			tasks = append(tasks, s.timed(urlConfig, viewport, phaseWait,
+				emulation.SetDeviceMetricsOverride(int64(viewport.Width), int64(viewport.Height), 1, false))...)
+		}
+		tasks = append(tasks, s.timed(urlConfig, viewport, phaseWait, s.waitForPage(urlConfig))...)
+		tasks = append(tasks, s.timed(urlConfig, viewport, phaseScroll, s.lazyLoadScroll(urlConfig, viewportDir)...)...)
+		tasks = append(tasks, s.timed(urlConfig, viewport, phaseWait, chromedp.Sleep(1*time.Second))...)
+		tasks = append(tasks, s.timed(urlConfig, viewport, phaseWait, s.waitBeforeCapture(urlConfig, viewport)...)...)
+		tasks = append(tasks, s.timed(urlConfig, viewport, phaseCapture, chromedp.ActionFunc(func(ctx context.Context) error {
+			return s.captureFullPage(ctx, urlConfig, viewport, &buf)
+		}))...)
+		if err := chromedp.Run(browserCtx, s.slowMo(tasks)...); err != nil {
+			return fmt.Errorf("failed to capture full page screenshot for %s at viewport %dx%d: %w",
+				urlConfig.Name, viewport.Width, viewport.Height, err)
+		}
+
+		filename := fmt.Sprintf("%s-full-%dx%d.%s", fileTimestamp(), viewport.Width, viewport.Height, s.format(urlConfig))
+		path, err := s.writeImage(urlConfig, viewport, "full", filepath.Join(viewportDir, filename), buf)
+		if err != nil {
+			return err
+		}
+		log.Printf("Captured full page screenshot for %s at viewport %dx%d: %s", urlConfig.Name, viewport.Width, viewport.Height, path)
+
+		viewportHAR := har
+		if i > 0 {
+			viewportHAR = nil
+		}
+		if err := s.saveArtifacts(browserCtx, urlConfig, viewportDir, viewportHAR); err != nil {
+			return fmt.Errorf("failed to save artifacts for %s at viewport %dx%d: %w",
+				urlConfig.Name, viewport.Width, viewport.Height, err)
+		}
+	}
+
+	log.Printf("Swept %s across %d viewports in %v", urlConfig.Name, len(viewports), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// capturesBesideFullPage reports whether a viewport whose full page capture was swept still needs
+// a page load of its own for its other captures
+func (s *Screenshoter) capturesBesideFullPage(urlConfig config.URLConfig, sections, withViewProof bool) bool {
+	return (sections && s.Config.CaptureMode != config.CaptureModeFull) || withViewProof ||
+		s.Config.CaptureHero || urlConfig.Filmstrip != nil || urlConfig.EachSelector != ""
+}
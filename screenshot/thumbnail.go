@@ -0,0 +1,101 @@
+package screenshot
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+// thumbnailPath returns where the thumbnail of the image at path is written
+func thumbnailPath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-thumb" + ext
+}
+
+// writeThumbnail writes a copy of the image at path scaled down to Config.ThumbnailWidth,
+// keeping the aspect ratio and format, and returns the path written. Images already at most
+// that wide are not scaled up; their own path is returned instead.
+func (s *Screenshoter) writeThumbnail(path string, data []byte, quality int) (string, error) {
+	img, err := decodeImage(data)
+	if err != nil {
+		return "", err
+	}
+	bounds := img.Bounds()
+	if bounds.Dx() <= s.Config.ThumbnailWidth {
+		return path, nil
+	}
+
+	height := bounds.Dy() * s.Config.ThumbnailWidth / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+	thumb := downscale(img, s.Config.ThumbnailWidth, height)
+
+	var out []byte
+	if strings.EqualFold(filepath.Ext(path), ".jpeg") {
+		out, err = encodeJPEG(thumb, quality, s.Config.ProgressiveJPEG)
+	} else {
+		out, err = encodePNG(thumb, pngCompressionLevels[s.Config.PNGCompression])
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+
+	thumbPath := thumbnailPath(path)
+	if err := s.Sink.Write(thumbPath, out); err != nil {
+		return "", err
+	}
+	log.Printf("Saved %dx%d thumbnail: %s", s.Config.ThumbnailWidth, height, thumbPath)
+	return thumbPath, nil
+}
+
+// downscale resizes img to width x height by averaging the source pixels that fall into each
+// destination pixel, weighted by how much of them it covers. It is only meant for shrinking.
+func downscale(img image.Image, width, height int) *image.RGBA {
+	src := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	scaleX := float64(src.Dx()) / float64(width)
+	scaleY := float64(src.Dy()) / float64(height)
+
+	for dy := 0; dy < height; dy++ {
+		y0, y1 := float64(dy)*scaleY, float64(dy+1)*scaleY
+		for dx := 0; dx < width; dx++ {
+			x0, x1 := float64(dx)*scaleX, float64(dx+1)*scaleX
+
+			var r, g, b, a, total float64
+			for sy := int(y0); float64(sy) < y1 && sy < src.Dy(); sy++ {
+				wy := coverage(y0, y1, sy)
+				for sx := int(x0); float64(sx) < x1 && sx < src.Dx(); sx++ {
+					w := wy * coverage(x0, x1, sx)
+					cr, cg, cb, ca := img.At(src.Min.X+sx, src.Min.Y+sy).RGBA()
+					r += float64(cr) * w
+					g += float64(cg) * w
+					b += float64(cb) * w
+					a += float64(ca) * w
+					total += w
+				}
+			}
+
+			i := dst.PixOffset(dx, dy)
+			dst.Pix[i+0] = uint8(r/total/257 + 0.5)
+			dst.Pix[i+1] = uint8(g/total/257 + 0.5)
+			dst.Pix[i+2] = uint8(b/total/257 + 0.5)
+			dst.Pix[i+3] = uint8(a/total/257 + 0.5)
+		}
+	}
+	return dst
+}
+
+// coverage returns how much of the source pixel at index p lies within [lo, hi)
+func coverage(lo, hi float64, p int) float64 {
+	start, end := float64(p), float64(p+1)
+	if lo > start {
+		start = lo
+	}
+	if hi < end {
+		end = hi
+	}
+	return end - start
+}
@@ -0,0 +1,136 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Capture phases measured when Config.Timing is set
+const (
+	phaseNavigation = "navigation" // Loading the page, including reloads after applying storage
+	phaseStorage    = "storage"    // Logging and applying cookies and localStorage
+	phaseScroll     = "scroll"     // Lazy-load and section scrolling
+	phaseWait       = "wait"       // Fixed sleeps and readiness waits
+	phaseCapture    = "capture"    // Taking the screenshots in Chrome
+	phaseWrite      = "write"      // Processing and writing the images
+)
+
+// timingPhases lists the phases in report order
+var timingPhases = []string{phaseNavigation, phaseStorage, phaseScroll, phaseWait, phaseCapture, phaseWrite}
+
+// captureTimings accumulates the time spent in each phase per captured viewport. Sections are
+// captured in parallel, so their phases can add up to more than the wall-clock time.
+type captureTimings struct {
+	mu        sync.Mutex
+	viewports map[string]map[string]time.Duration
+}
+
+func newCaptureTimings() *captureTimings {
+	return &captureTimings{viewports: make(map[string]map[string]time.Duration)}
+}
+
+// timingKey names the viewport a measurement belongs to in the report
+func timingKey(urlConfig config.URLConfig, viewport config.Viewport) string {
+	if urlConfig.ActiveProfile != "" {
+		return fmt.Sprintf("%s (%s) at %s", urlConfig.Name, urlConfig.ActiveProfile, viewport)
+	}
+	return fmt.Sprintf("%s at %s", urlConfig.Name, viewport)
+}
+
+// add records d against phase for the viewport named key. It does nothing on a nil receiver,
+// so callers need not check whether timing is enabled.
+func (t *captureTimings) add(key, phase string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.viewports[key] == nil {
+		t.viewports[key] = make(map[string]time.Duration)
+	}
+	t.viewports[key][phase] += d
+}
+
+// report logs each viewport's breakdown, slowest first, then the totals across all viewports
+func (t *captureTimings) report() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.viewports) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(t.viewports))
+	totals := make(map[string]time.Duration)
+	aggregate := make(map[string]time.Duration)
+	for key, phases := range t.viewports {
+		keys = append(keys, key)
+		for phase, d := range phases {
+			totals[key] += d
+			aggregate[phase] += d
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if totals[keys[i]] != totals[keys[j]] {
+			return totals[keys[i]] > totals[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	for _, key := range keys {
+		log.Printf("Timing for %s: %s", key, formatPhases(t.viewports[key]))
+	}
+	log.Printf("Timing across %d viewports: %s", len(keys), formatPhases(aggregate))
+}
+
+// formatPhases renders the phases in report order with their share of the total
+func formatPhases(phases map[string]time.Duration) string {
+	var total time.Duration
+	for _, d := range phases {
+		total += d
+	}
+	parts := make([]string, 0, len(timingPhases))
+	for _, phase := range timingPhases {
+		d, ok := phases[phase]
+		if !ok {
+			continue
+		}
+		share := 0.0
+		if total > 0 {
+			share = float64(d) / float64(total) * 100
+		}
+		parts = append(parts, fmt.Sprintf("%s %v (%.0f%%)", phase, d.Round(time.Millisecond), share))
+	}
+	return fmt.Sprintf("%s; total %v", strings.Join(parts, ", "), total.Round(time.Millisecond))
+}
+
+// timed wraps actions so the time they take counts toward phase for the viewport. Without
+// Config.Timing the actions are returned unchanged.
+func (s *Screenshoter) timed(urlConfig config.URLConfig, viewport config.Viewport, phase string, actions ...chromedp.Action) []chromedp.Action {
+	if s.timings == nil || len(actions) == 0 {
+		return actions
+	}
+	key := timingKey(urlConfig, viewport)
+	var start time.Time
+	timed := make([]chromedp.Action, 0, len(actions)+2)
+	timed = append(timed, chromedp.ActionFunc(func(context.Context) error {
+		start = time.Now()
+		return nil
+	}))
+	timed = append(timed, actions...)
+	return append(timed, chromedp.ActionFunc(func(context.Context) error {
+		s.timings.add(key, phase, time.Since(start))
+		return nil
+	}))
+}
@@ -1,10 +1,38 @@
 package screenshot
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 )
 
+// fileTimestampLayout formats the timestamp that prefixes image filenames, to the millisecond
+const fileTimestampLayout = "20060102-150405.000"
+
+var (
+	fileTimestampMu   sync.Mutex
+	lastFileTimestamp time.Time
+)
+
+// fileTimestamp returns the current time formatted for an image filename prefix. Every call
+// in the process gets a distinct timestamp, one millisecond after the previous one if the
+// clock has not moved on, so captures of the same type in the same directory never share a
+// filename.
+func fileTimestamp() string {
+	fileTimestampMu.Lock()
+	defer fileTimestampMu.Unlock()
+	now := time.Now().Truncate(time.Millisecond)
+	if !now.After(lastFileTimestamp) {
+		now = lastFileTimestamp.Add(time.Millisecond)
+	}
+	lastFileTimestamp = now
+	return now.Format(fileTimestampLayout)
+}
+
 // sanitizeFilename sanitizes a filename by removing illegal characters
 func sanitizeFilename(filename string) string {
 	// Replace illegal characters with underscore
@@ -21,3 +49,43 @@ func sanitizeFilename(filename string) string {
 
 	return sanitized
 }
+
+// createUniqueDir creates a new directory named base inside parent. If a directory with
+// that name already exists (for example two same-named URLs starting in the same second),
+// a numeric suffix is appended until an unused name is found. os.Mkdir fails atomically
+// on existing paths, so concurrent callers never end up sharing a directory.
+func createUniqueDir(parent, base string) (string, error) {
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", err
+	}
+
+	name := base
+	for i := 2; ; i++ {
+		dir := filepath.Join(parent, name)
+		err := os.Mkdir(dir, 0755)
+		if err == nil {
+			return dir, nil
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// createUniqueFile creates a new file named base+ext inside parent and opens it for
+// writing. Like createUniqueDir it appends a numeric suffix to base while the name is
+// taken, and O_EXCL makes concurrent callers end up with different files.
+func createUniqueFile(parent, base, ext string) (*os.File, error) {
+	name := base
+	for i := 2; ; i++ {
+		file, err := os.OpenFile(filepath.Join(parent, name+ext), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			return file, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+}
@@ -0,0 +1,92 @@
+package screenshot
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCreateUniqueDirConcurrent(t *testing.T) {
+	const n = 50
+	parent := filepath.Join(t.TempDir(), "out")
+
+	dirs := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dirs[i], errs[i] = createUniqueDir(parent, "example_20250101-120000")
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for i, dir := range dirs {
+		if errs[i] != nil {
+			t.Fatalf("call %d failed: %v", i, errs[i])
+		}
+		if seen[dir] {
+			t.Errorf("directory %s returned twice", dir)
+		}
+		seen[dir] = true
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			t.Errorf("%s is not a directory: %v", dir, err)
+		}
+	}
+
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != n {
+		t.Errorf("%d directories created, want %d", len(entries), n)
+	}
+}
+
+func TestFileTimestampUnique(t *testing.T) {
+	const goroutines, calls = 8, 2000
+
+	stamps := make([][]string, goroutines)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < calls; i++ {
+				stamps[g] = append(stamps[g], fileTimestamp())
+			}
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for _, list := range stamps {
+		var previous time.Time
+		for _, stamp := range list {
+			if seen[stamp] {
+				t.Fatalf("timestamp %s handed out twice", stamp)
+			}
+			seen[stamp] = true
+
+			parsed, err := time.ParseInLocation(fileTimestampLayout, stamp, time.Local)
+			if err != nil {
+				t.Fatalf("timestamp %s does not parse: %v", stamp, err)
+			}
+			if !parsed.After(previous) {
+				t.Errorf("timestamp %s is not after the previous one from the same goroutine", stamp)
+			}
+			previous = parsed
+		}
+	}
+
+	// Watch mode matches images across runs by the name after the timestamp
+	first := watchKey("home", "", "1280x800", "/out/"+fileTimestamp()+"-full.png")
+	second := watchKey("home", "", "1280x800", "/out/"+fileTimestamp()+"-full.png")
+	if first != second || first != "home||1280x800|full.png" {
+		t.Errorf("watchKey does not strip the timestamp: %q, %q", first, second)
+	}
+}
@@ -0,0 +1,530 @@
+package screenshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"screenshot-tool/config"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// pageNowJS reads the page's clock in milliseconds. FixedTime pins Date.now, so waits that time a
+// quiet period use the real clock the fixed clock script saves first.
+const pageNowJS = `(window.__screenshotRealNow || Date.now)()`
+
+// domStableObserverScript installs a MutationObserver that records the time of the last DOM mutation
+const domStableObserverScript = `
+(function() {
+	if (!window.__screenshotDomStable) {
+		window.__screenshotDomStable = { last: ` + pageNowJS + ` };
+		new MutationObserver(function() {
+			window.__screenshotDomStable.last = ` + pageNowJS + `;
+		}).observe(document.documentElement, {
+			childList: true,
+			subtree: true,
+			attributes: true,
+			characterData: true
+		});
+	}
+	return true;
+})()`
+
+// waitForPage returns the action that lets the page settle after navigation, according to the URL's wait strategy
+func (s *Screenshoter) waitForPage(urlConfig config.URLConfig) chromedp.Action {
+	switch urlConfig.WaitStrategy {
+	case config.WaitDOMStable:
+		return s.waitForDOMStable(urlConfig)
+	case config.WaitNetworkIdle:
+		return waitForNetworkIdle(urlConfig)
+	default:
+		return chromedp.Sleep(time.Duration(urlConfig.Delay) * time.Millisecond)
+	}
+}
+
+// scrollSettle returns the pause that lets lazy content react after a scroll
+func (s *Screenshoter) scrollSettle() chromedp.Action {
+	return chromedp.Sleep(time.Duration(s.Config.ScrollSettleMs) * time.Millisecond)
+}
+
+// forceEagerImagesScript switches native lazy images to eager loading and forces a reflow so
+// Chrome starts fetching them. Headless Chrome often never loads them below the fold otherwise.
+const forceEagerImagesScript = `
+(function() {
+	var images = document.querySelectorAll('img[loading="lazy"]');
+	for (var i = 0; i < images.length; i++) {
+		images[i].setAttribute('loading', 'eager');
+	}
+	void document.body.offsetHeight;
+	return images.length;
+})()`
+
+// forceEagerImages loads every img[loading=lazy] on the page right away
+func (s *Screenshoter) forceEagerImages(urlConfig config.URLConfig) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var count int
+		if err := chromedp.Evaluate(forceEagerImagesScript, &count).Do(ctx); err != nil {
+			return fmt.Errorf("failed to force eager image loading: %w", err)
+		}
+		if count > 0 {
+			log.Printf("Forced eager loading of %d lazy images on %s", count, urlConfig.Name)
+		}
+		return nil
+	})
+}
+
+// lazyLoadScroll returns the actions that scroll to the bottom of the page and back to the top,
+// pausing after each, so lazy-loaded content is triggered before capture. With MaxScrolls the
+// page is scrolled down repeatedly first, for infinite feeds. ForceEagerImages runs first, even
+// when the URL disables automatic scrolling.
+func (s *Screenshoter) lazyLoadScroll(urlConfig config.URLConfig, viewportDir string) []chromedp.Action {
+	var actions []chromedp.Action
+	if s.Config.ForceEagerImages {
+		actions = append(actions, s.forceEagerImages(urlConfig))
+	}
+	if urlConfig.DisableAutoScroll {
+		return actions
+	}
+	scrollDown := chromedp.Action(chromedp.Tasks{
+		chromedp.Evaluate(`window.scrollTo(0, document.body.scrollHeight)`, nil),
+		s.scrollSettle(),
+	})
+	if urlConfig.MaxScrolls > 0 {
+		scrollDown = s.scrollFeed(urlConfig, viewportDir)
+	}
+	return append(actions,
+		scrollDown,
+		chromedp.Evaluate(`window.scrollTo(0, 0)`, nil),
+		s.scrollSettle(),
+	)
+}
+
+// scrollFeed scrolls to the bottom up to MaxScrolls times, pausing ScrollPauseMs after each, and
+// stops early once the page height stops growing. The number of scrolls is recorded for the
+// images captured next in viewportDir.
+func (s *Screenshoter) scrollFeed(urlConfig config.URLConfig, viewportDir string) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		pause := time.Duration(urlConfig.ScrollPauseMs) * time.Millisecond
+		if pause == 0 {
+			pause = time.Duration(s.Config.ScrollSettleMs) * time.Millisecond
+		}
+
+		const heightJS = `Math.max(document.body.scrollHeight, document.documentElement.scrollHeight)`
+		var height float64
+		if err := chromedp.Evaluate(heightJS, &height).Do(ctx); err != nil {
+			return err
+		}
+
+		scrolls := 0
+		for scrolls < urlConfig.MaxScrolls {
+			if err := chromedp.Evaluate(`window.scrollTo(0, document.body.scrollHeight)`, nil).Do(ctx); err != nil {
+				return err
+			}
+			if err := chromedp.Sleep(pause).Do(ctx); err != nil {
+				return err
+			}
+			scrolls++
+
+			var grown float64
+			if err := chromedp.Evaluate(heightJS, &grown).Do(ctx); err != nil {
+				return err
+			}
+			if grown <= height {
+				break
+			}
+			height = grown
+		}
+
+		log.Printf("Scrolled %s %d times (max %d), page height now %.0fpx", urlConfig.Name, scrolls, urlConfig.MaxScrolls, height)
+		s.Manifest.setScrolls(viewportDir, scrolls)
+		return nil
+	})
+}
+
+// waitForDOMStable waits until the DOM has not changed for the configured quiet window.
+// Reaching the hard cap is logged but does not fail the capture.
+func (s *Screenshoter) waitForDOMStable(urlConfig config.URLConfig) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		quiet := time.Duration(s.Config.DOMStableQuietMs) * time.Millisecond
+		timeout := time.Duration(s.Config.DOMStableTimeoutMs) * time.Millisecond
+		start := time.Now()
+
+		if err := chromedp.Evaluate(domStableObserverScript, nil).Do(ctx); err != nil {
+			return fmt.Errorf("failed to install DOM mutation observer: %w", err)
+		}
+
+		predicate := fmt.Sprintf(pageNowJS+` - window.__screenshotDomStable.last >= %d`, quiet.Milliseconds())
+		err := chromedp.Poll(predicate, nil,
+			chromedp.WithPollingInterval(100*time.Millisecond),
+			chromedp.WithPollingTimeout(timeout),
+		).Do(ctx)
+		if errors.Is(err, chromedp.ErrPollingTimeout) {
+			log.Printf("Warning: DOM of %s still changing after %v, continuing", urlConfig.Name, timeout)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		log.Printf("DOM of %s stable for %v after %v", urlConfig.Name, quiet, time.Since(start).Round(time.Millisecond))
+		return nil
+	})
+}
+
+// Quiet window and hard cap of the networkidle wait strategy
+const (
+	networkIdleQuiet   = 500 * time.Millisecond
+	networkIdleTimeout = 30 * time.Second
+)
+
+// waitForNetworkIdle waits until the tab has had no requests in flight, and no network activity,
+// for networkIdleQuiet. Only requests seen after the wait starts are counted. Reaching
+// networkIdleTimeout is logged but does not fail the capture, since pages that poll never go idle.
+func waitForNetworkIdle(urlConfig config.URLConfig) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		start := time.Now()
+		var mu sync.Mutex
+		inFlight := make(map[network.RequestID]bool)
+		lastActivity := start
+
+		listenCtx, cancelListen := context.WithCancel(ctx)
+		defer cancelListen()
+		chromedp.ListenTarget(listenCtx, func(ev interface{}) {
+			mu.Lock()
+			defer mu.Unlock()
+			switch ev := ev.(type) {
+			case *network.EventRequestWillBeSent:
+				inFlight[ev.RequestID] = true
+			case *network.EventLoadingFinished:
+				delete(inFlight, ev.RequestID)
+			case *network.EventLoadingFailed:
+				delete(inFlight, ev.RequestID)
+			default:
+				return
+			}
+			lastActivity = time.Now()
+		})
+
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			mu.Lock()
+			pending, quiet := len(inFlight), time.Since(lastActivity)
+			mu.Unlock()
+			if pending == 0 && quiet >= networkIdleQuiet {
+				log.Printf("Network of %s idle for %v after %v", urlConfig.Name, networkIdleQuiet, time.Since(start).Round(time.Millisecond))
+				return nil
+			}
+			if time.Since(start) >= networkIdleTimeout {
+				log.Printf("Warning: %d requests of %s still in flight after %v, continuing", pending, urlConfig.Name, networkIdleTimeout)
+				return nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// waitBeforeCapture returns the readiness checks that run right before screenshots are taken in every capture path
+func (s *Screenshoter) waitBeforeCapture(urlConfig config.URLConfig, viewport config.Viewport) []chromedp.Action {
+	var actions []chromedp.Action
+	// Resizing goes first so the checks below see the layout it produces
+	if s.Config.TriggerResize {
+		actions = append(actions, s.triggerResize(urlConfig, viewport))
+	}
+	if urlConfig.RouteReadySelector != "" || urlConfig.RouteURLPattern != "" {
+		actions = append(actions, waitForRoute(urlConfig))
+	}
+	if s.Config.AutoAcceptConsent {
+		actions = append(actions, s.acceptConsent(urlConfig))
+	}
+	if urlConfig.FrameSelector != "" {
+		actions = append(actions, checkFrame(urlConfig))
+	}
+	if s.Config.WaitForFonts {
+		actions = append(actions, s.waitForFonts(urlConfig))
+	}
+	if urlConfig.ReadyExpression != "" {
+		actions = append(actions, waitForReadyExpression(urlConfig))
+	}
+	if urlConfig.StableSelector != "" {
+		actions = append(actions, waitForStableSelector(urlConfig))
+	}
+	if s.Config.WaitForImages {
+		actions = append(actions, s.waitForImages(urlConfig))
+	}
+	return actions
+}
+
+// triggerResizePause lets resize listeners run after each size change of triggerResize
+const triggerResizePause = 250 * time.Millisecond
+
+// triggerResize renders the page at twice the viewport's size, or half of it when that would be
+// too large, and then at the viewport's size again, dispatching a resize event after each. Layouts
+// computed by resize listeners rather than on load then match the viewport.
+func (s *Screenshoter) triggerResize(urlConfig config.URLConfig, viewport config.Viewport) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		width, height := viewport.Width*2, viewport.Height*2
+		if width > config.MaxViewportSize || height > config.MaxViewportSize {
+			width, height = max(viewport.Width/2, config.MinViewportSize), max(viewport.Height/2, config.MinViewportSize)
+		}
+
+		log.Printf("Triggering resize on %s: %dx%d, then back to %dx%d", urlConfig.Name, width, height, viewport.Width, viewport.Height)
+		for _, size := range [][2]int{{width, height}, {viewport.Width, viewport.Height}} {
+			if err := emulation.SetDeviceMetricsOverride(int64(size[0]), int64(size[1]), 1, false).Do(ctx); err != nil {
+				return fmt.Errorf("failed to resize to %dx%d: %w", size[0], size[1], err)
+			}
+			if err := chromedp.Evaluate(`window.dispatchEvent(new Event('resize'))`, nil).Do(ctx); err != nil {
+				return fmt.Errorf("failed to dispatch resize event: %w", err)
+			}
+			if err := chromedp.Sleep(triggerResizePause).Do(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// waitForReadyExpression polls the URL's ready expression, evaluated in its frame when FrameSelector
+// is set, until it is truthy, failing with the expression in the error if it does not become truthy
+// within ReadyTimeoutMs
+func waitForReadyExpression(urlConfig config.URLConfig) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		timeout := time.Duration(urlConfig.ReadyTimeoutMs) * time.Millisecond
+		start := time.Now()
+
+		err := chromedp.Poll(inFrame(urlConfig, urlConfig.ReadyExpression), nil,
+			chromedp.WithPollingInterval(100*time.Millisecond),
+			chromedp.WithPollingTimeout(timeout),
+		).Do(ctx)
+		if errors.Is(err, chromedp.ErrPollingTimeout) {
+			return fmt.Errorf("ready expression %q was not truthy within %v", urlConfig.ReadyExpression, timeout)
+		}
+		if err != nil {
+			return fmt.Errorf("ready expression %q failed: %w", urlConfig.ReadyExpression, err)
+		}
+
+		log.Printf("Ready expression for %s became truthy after %v", urlConfig.Name, time.Since(start).Round(time.Millisecond))
+		return nil
+	})
+}
+
+// stableSelectorScript reports whether the element matching the selector has kept its size for
+// the quiet window. The size is checked on every poll, and a ResizeObserver on the element also
+// catches changes in between; both restart the window. A missing element is never stable.
+const stableSelectorScript = `
+(function(selector, quiet) {
+	var state = window.__screenshotStableSelector;
+	if (!state || state.selector !== selector) {
+		state = window.__screenshotStableSelector = {selector: selector, size: null, last: ` + pageNowJS + `};
+	}
+	var element = document.querySelector(selector);
+	var size = null;
+	if (element) {
+		var rect = element.getBoundingClientRect();
+		size = rect.width + 'x' + rect.height;
+		if (window.ResizeObserver && state.observed !== element) {
+			if (state.observer) {
+				state.observer.disconnect();
+			}
+			state.observer = new ResizeObserver(function() {
+				state.last = ` + pageNowJS + `;
+			});
+			state.observer.observe(element);
+			state.observed = element;
+		}
+	}
+	if (size !== state.size) {
+		state.size = size;
+		state.last = ` + pageNowJS + `;
+	}
+	return {found: size !== null, size: size, stable: size !== null && ` + pageNowJS + ` - state.last >= quiet};
+})("%s", %d)`
+
+// waitForStableSelector waits until the element matching StableSelector, in the URL's frame if
+// any, has not changed size for StableQuietMs, so charts and canvases that render after layout are
+// captured once they have settled. Reaching StableTimeoutMs is logged but does not fail the capture.
+func waitForStableSelector(urlConfig config.URLConfig) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		quiet := time.Duration(urlConfig.StableQuietMs) * time.Millisecond
+		timeout := time.Duration(urlConfig.StableTimeoutMs) * time.Millisecond
+		script := inFrame(urlConfig, fmt.Sprintf(stableSelectorScript, escapeJSString(urlConfig.StableSelector), quiet.Milliseconds()))
+		start := time.Now()
+
+		var state struct {
+			Found  bool   `json:"found"`
+			Size   string `json:"size"`
+			Stable bool   `json:"stable"`
+		}
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			if err := chromedp.Evaluate(script, &state).Do(ctx); err != nil {
+				return fmt.Errorf("failed to check stableSelector %q: %w", urlConfig.StableSelector, err)
+			}
+			if state.Stable {
+				log.Printf("Stable selector %q on %s settled at %s after %v", urlConfig.StableSelector, urlConfig.Name, state.Size, time.Since(start).Round(time.Millisecond))
+				return nil
+			}
+			if time.Since(start) >= timeout {
+				if state.Found {
+					log.Printf("Warning: stable selector %q on %s still resizing after %v (now %s), continuing", urlConfig.StableSelector, urlConfig.Name, timeout, state.Size)
+				} else {
+					log.Printf("Warning: stable selector %q not found on %s after %v, continuing", urlConfig.StableSelector, urlConfig.Name, timeout)
+				}
+				return nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// waitForRoute waits for a single-page app to finish client-side routing: until RouteReadySelector
+// matches an element or the page's location matches RouteURLPattern, whichever happens first.
+// If neither happens within ReadyTimeoutMs, the error describes the state that was expected.
+func waitForRoute(urlConfig config.URLConfig) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		timeout := time.Duration(urlConfig.ReadyTimeoutMs) * time.Millisecond
+		start := time.Now()
+
+		var pattern *regexp.Regexp
+		if urlConfig.RouteURLPattern != "" {
+			var err error
+			if pattern, err = regexp.Compile(urlConfig.RouteURLPattern); err != nil {
+				return fmt.Errorf("invalid routeUrlPattern: %w", err)
+			}
+		}
+		found := "false"
+		if urlConfig.RouteReadySelector != "" {
+			found = fmt.Sprintf(`document.querySelector("%s") !== null`, escapeJSString(urlConfig.RouteReadySelector))
+		}
+		script := fmt.Sprintf(`({found: %s, href: window.location.href})`, found)
+
+		var state struct {
+			Found bool   `json:"found"`
+			Href  string `json:"href"`
+		}
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			if err := chromedp.Evaluate(script, &state).Do(ctx); err != nil {
+				return fmt.Errorf("failed to check route: %w", err)
+			}
+			if state.Found {
+				log.Printf("Route selector %q for %s appeared after %v", urlConfig.RouteReadySelector, urlConfig.Name, time.Since(start).Round(time.Millisecond))
+				return nil
+			}
+			if pattern != nil && pattern.MatchString(state.Href) {
+				log.Printf("Route of %s reached %s after %v", urlConfig.Name, state.Href, time.Since(start).Round(time.Millisecond))
+				return nil
+			}
+			if time.Since(start) >= timeout {
+				return fmt.Errorf("route not ready within %v: expected %s, page is at %s", timeout, expectedRoute(urlConfig), state.Href)
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// expectedRoute describes the route state waitForRoute waits for
+func expectedRoute(urlConfig config.URLConfig) string {
+	var expected []string
+	if urlConfig.RouteReadySelector != "" {
+		expected = append(expected, fmt.Sprintf("selector %q to appear", urlConfig.RouteReadySelector))
+	}
+	if urlConfig.RouteURLPattern != "" {
+		expected = append(expected, fmt.Sprintf("location to match %q", urlConfig.RouteURLPattern))
+	}
+	return strings.Join(expected, " or ")
+}
+
+// waitForFonts waits for document.fonts to finish loading so text is not captured in fallback fonts.
+// A font promise that never settles only delays the capture by FontTimeoutMs.
+func (s *Screenshoter) waitForFonts(urlConfig config.URLConfig) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		timeout := time.Duration(s.Config.FontTimeoutMs) * time.Millisecond
+		err := chromedp.Poll(`!document.fonts || document.fonts.status === 'loaded'`, nil,
+			chromedp.WithPollingInterval(50*time.Millisecond),
+			chromedp.WithPollingTimeout(timeout),
+		).Do(ctx)
+		if errors.Is(err, chromedp.ErrPollingTimeout) {
+			log.Printf("Warning: fonts for %s still loading after %v, continuing", urlConfig.Name, timeout)
+			return nil
+		}
+		return err
+	})
+}
+
+// pendingImagesScript counts the <img> elements, within the viewport unless all are wanted, that
+// have not finished loading with real pixels
+const pendingImagesScript = `
+(function(all) {
+	var pending = 0;
+	var images = document.images;
+	for (var i = 0; i < images.length; i++) {
+		var img = images[i];
+		if (!all) {
+			var rect = img.getBoundingClientRect();
+			if (rect.width === 0 || rect.height === 0 || rect.bottom <= 0 || rect.right <= 0 ||
+				rect.top >= window.innerHeight || rect.left >= window.innerWidth) {
+				continue;
+			}
+		}
+		if (!img.complete || img.naturalWidth === 0) {
+			pending++;
+		}
+	}
+	return pending;
+})(%t)`
+
+// waitForImages waits until every <img> in scope reports complete with a natural width, so lazy
+// images are not captured half-loaded. Reaching ImageTimeoutMs is logged but does not fail the
+// capture, since broken images never finish.
+func (s *Screenshoter) waitForImages(urlConfig config.URLConfig) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		timeout := time.Duration(s.Config.ImageTimeoutMs) * time.Millisecond
+		script := fmt.Sprintf(pendingImagesScript, s.Config.ImagesScope == config.ImagesAll)
+		start := time.Now()
+
+		err := chromedp.Poll(script+` === 0`, nil,
+			chromedp.WithPollingInterval(100*time.Millisecond),
+			chromedp.WithPollingTimeout(timeout),
+		).Do(ctx)
+		if errors.Is(err, chromedp.ErrPollingTimeout) {
+			var pending int
+			if err := chromedp.Evaluate(script, &pending).Do(ctx); err != nil {
+				return err
+			}
+			log.Printf("Warning: %d images on %s still loading after %v, continuing", pending, urlConfig.Name, timeout)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Images on %s loaded after %v", urlConfig.Name, time.Since(start).Round(time.Millisecond))
+		return nil
+	})
+}
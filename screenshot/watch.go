@@ -0,0 +1,101 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"screenshot-tool/config"
+)
+
+// Watch repeatedly captures all configured URLs every WatchIntervalSec seconds until ctx is
+// cancelled. After each run, images identical to the last kept capture of the same URL,
+// viewport and section are deleted, so only changed images accumulate on disk (each under
+// its own timestamped name). Failed runs are logged and retried on the next tick.
+func (s *Screenshoter) Watch(ctx context.Context) error {
+	if !s.writesFiles() {
+		return fmt.Errorf("watch mode compares captures on disk and requires output %q", config.OutputFile)
+	}
+
+	interval := time.Duration(s.Config.WatchIntervalSec) * time.Second
+	previous := make(map[string]string) // image key -> path of the last kept capture
+
+	s.watching = true
+	defer func() { s.watching = false }()
+	if s.Config.ZipOutput {
+		log.Printf("Warning: zipOutput is ignored in watch mode, images are kept as loose files")
+	}
+
+	for run := 1; ; run++ {
+		log.Printf("Watch run #%d starting", run)
+		if err := s.CaptureURLs(ctx); err != nil {
+			log.Printf("Watch run #%d failed: %v", run, err)
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		changed, unchanged := s.pruneUnchanged(previous)
+		log.Printf("Watch run #%d finished: %d changed, %d unchanged; next run in %v", run, changed, unchanged, interval)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// pruneUnchanged compares the images of the latest run with the previously kept ones,
+// deleting new images that did not change and updating the manifest to point at the kept copy
+func (s *Screenshoter) pruneUnchanged(previous map[string]string) (int, int) {
+	changed, unchanged := 0, 0
+
+	for i := range s.Manifest.Files {
+		file := &s.Manifest.Files[i]
+		key := watchKey(file.Name, file.Profile, file.Viewport, file.Path)
+
+		if prevPath, ok := previous[key]; ok {
+			same, err := sameImageFiles(prevPath, file.Path)
+			if err != nil {
+				log.Printf("Warning: could not compare %s with %s: %v", file.Path, prevPath, err)
+			} else if same {
+				if err := os.Remove(file.Path); err != nil {
+					log.Printf("Warning: failed to remove unchanged image %s: %v", file.Path, err)
+				} else {
+					file.Path = prevPath
+					file.Unchanged = true
+					unchanged++
+					continue
+				}
+			}
+		}
+
+		log.Printf("Image changed: %s", file.Path)
+		previous[key] = file.Path
+		changed++
+	}
+
+	if _, err := s.Manifest.writeManifest(s.Config.OutputDir); err != nil {
+		log.Printf("ERROR: %v", err)
+	}
+
+	return changed, unchanged
+}
+
+// watchKey identifies the same image across runs by dropping the timestamp prefix of its filename
+func watchKey(name, profile, viewport, path string) string {
+	base := filepath.Base(path)
+	if idx := strings.Index(base, "-"); idx >= 0 {
+		// Timestamps are formatted as 20060102-150405.000, so skip past both parts
+		if next := strings.Index(base[idx+1:], "-"); next >= 0 {
+			base = base[idx+1+next+1:]
+		}
+	}
+	return name + "|" + profile + "|" + viewport + "|" + base
+}